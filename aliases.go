@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	"go.xrstf.de/gimps/pkg/gimps"
+)
+
+// noAlias is used in the report for imports without an alias.
+const noAlias = "(none)"
+
+// aliasUsage maps import paths to the aliases they are imported
+// under, and each alias to the (relative) files that use it.
+type aliasUsage map[string]map[string][]string
+
+// runAliases implements the "gimps aliases" subcommand, which reports how
+// packages are aliased throughout the module.
+func runAliases(args []string) {
+	configFile := ""
+	onlyInconsistent := false
+
+	flags := pflag.NewFlagSet("aliases", pflag.ExitOnError)
+	flags.StringVarP(&configFile, "config", "c", configFile, "Path to the config file (mandatory).")
+	flags.BoolVarP(&onlyInconsistent, "inconsistent", "i", onlyInconsistent, "Only list import paths that are imported under different aliases.")
+	flags.Parse(args)
+
+	// by default, report on the entire module, even when called in a subdirectory
+	inputs := flags.Args()
+	if len(inputs) == 0 {
+		cwd, err := os.Getwd()
+		if err != nil {
+			fatalf("Failed to determine working directory: %v.", err)
+		}
+
+		modRoot, err := goModRootPath(cwd)
+		if err != nil {
+			fatalf("No files or directories given and could not find the module root: %v.", err)
+		}
+
+		inputs = []string{modRoot}
+	}
+
+	inputs, err := cleanupArgs(inputs)
+	if err != nil {
+		fatalf("Invalid arguments: %v.", err)
+	}
+
+	modRoot, _ := goModRootPath(inputs[0])
+
+	config, err := loadConfiguration(configFile, modRoot)
+	if err != nil {
+		fatalf("Failed to load -config file %q: %v", configFile, err)
+	}
+
+	filenames := []string{}
+	for _, input := range inputs {
+		found, _, err := listFiles(input, modRoot, config.Exclude)
+		if err != nil {
+			fatalf("Failed to process %q: %v", input, err)
+		}
+
+		filenames = append(filenames, found...)
+	}
+
+	usage := aliasUsage{}
+	for _, filename := range filenames {
+		if *config.DetectGeneratedFiles {
+			generated, err := isGeneratedFile(filename)
+			if err != nil {
+				fatalf("Cannot check if %q is generated: %v", filename, err)
+			}
+
+			if generated {
+				continue
+			}
+		}
+
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			fatalf("Failed to read %q: %v", filename, err)
+		}
+
+		if err := usage.collect(mustRelPath(modRoot, filename), source); err != nil {
+			fatalf("Failed to parse %q: %v", filename, err)
+		}
+	}
+
+	usage.print(os.Stdout, onlyInconsistent)
+
+	suggestions := usage.suggestRules(config.AliasRules)
+	if len(suggestions) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("# Suggested alias rules to unify inconsistent imports:")
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(map[string][]gimps.AliasRule{"aliasRules": suggestions}); err != nil {
+		log.Printf("Failed to encode suggestions: %v", err)
+		os.Exit(exitCodeError)
+	}
+}
+
+// collect records the aliases of all imports in the given source code.
+// Blank and dot imports are ignored, as they cannot be unified.
+func (u aliasUsage) collect(relPath string, source []byte) error {
+	file, err := parser.ParseFile(token.NewFileSet(), relPath, source, parser.ImportsOnly)
+	if err != nil {
+		return err
+	}
+
+	for _, imprt := range file.Imports {
+		pkg, err := strconv.Unquote(imprt.Path.Value)
+		if err != nil {
+			return err
+		}
+
+		alias := noAlias
+		if imprt.Name != nil {
+			alias = imprt.Name.Name
+		}
+
+		if alias == "_" || alias == "." {
+			continue
+		}
+
+		if _, ok := u[pkg]; !ok {
+			u[pkg] = map[string][]string{}
+		}
+
+		u[pkg][alias] = append(u[pkg][alias], relPath)
+	}
+
+	return nil
+}
+
+func (u aliasUsage) packages() []string {
+	packages := []string{}
+	for pkg := range u {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+
+	return packages
+}
+
+// aliases returns the aliases of the given package, the most
+// commonly used alias first.
+func (u aliasUsage) aliases(pkg string) []string {
+	files := u[pkg]
+
+	aliases := []string{}
+	for alias := range files {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Slice(aliases, func(i, j int) bool {
+		a, b := aliases[i], aliases[j]
+		if len(files[a]) != len(files[b]) {
+			return len(files[a]) > len(files[b])
+		}
+
+		return a < b
+	})
+
+	return aliases
+}
+
+func (u aliasUsage) print(w io.Writer, onlyInconsistent bool) {
+	for _, pkg := range u.packages() {
+		aliases := u.aliases(pkg)
+		inconsistent := len(aliases) > 1
+
+		if onlyInconsistent && !inconsistent {
+			continue
+		}
+
+		if inconsistent {
+			fmt.Fprintf(w, "! %s (%d different aliases)\n", pkg, len(aliases))
+		} else {
+			fmt.Fprintf(w, "  %s\n", pkg)
+		}
+
+		for _, alias := range aliases {
+			files := u[pkg][alias]
+			fmt.Fprintf(w, "    %s (%d file(s))\n", alias, len(files))
+
+			if inconsistent {
+				for _, file := range files {
+					fmt.Fprintf(w, "      %s\n", file)
+				}
+			}
+		}
+	}
+}
+
+var nonRuleNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// suggestRules returns alias rules that would unify all inconsistently
+// aliased packages to their most commonly used alias. Packages that are
+// mostly used without an alias get an unalias rule instead. Packages that
+// are already covered by one of the existing rules are skipped.
+func (u aliasUsage) suggestRules(existing []gimps.AliasRule) []gimps.AliasRule {
+	existingExprs := []*regexp.Regexp{}
+	for _, rule := range existing {
+		if expr, err := regexp.Compile(rule.Expression); err == nil {
+			existingExprs = append(existingExprs, expr)
+		}
+	}
+
+	suggestions := []gimps.AliasRule{}
+
+packages:
+	for _, pkg := range u.packages() {
+		aliases := u.aliases(pkg)
+		if len(aliases) < 2 {
+			continue
+		}
+
+		for _, expr := range existingExprs {
+			if expr.MatchString(pkg) {
+				continue packages
+			}
+		}
+
+		rule := gimps.AliasRule{
+			Name:       strings.Trim(nonRuleNameChars.ReplaceAllString(strings.ToLower(pkg), "-"), "-"),
+			Expression: "^" + regexp.QuoteMeta(pkg) + "$",
+			Alias:      aliases[0],
+		}
+
+		if rule.Alias == noAlias {
+			rule.Type = gimps.AliasRuleTypeUnalias
+			rule.Alias = ""
+		}
+
+		suggestions = append(suggestions, rule)
+	}
+
+	return suggestions
+}
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"go.xrstf.de/gimps/pkg/gimps"
+)
+
+func TestAliasUsage(t *testing.T) {
+	sources := map[string]string{
+		"a.go": `package a
+
+import (
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+)
+`,
+		"b.go": `package b
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	ctrlruntime "sigs.k8s.io/controller-runtime"
+)
+`,
+		"c.go": `package c
+
+import (
+	"k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	yaml "gopkg.in/yaml.v3"
+)
+`,
+		"d.go": `package d
+
+import (
+	"gopkg.in/yaml.v3"
+)
+`,
+		"e.go": `package e
+
+import (
+	"gopkg.in/yaml.v3"
+)
+`,
+	}
+
+	usage := aliasUsage{}
+	for _, filename := range []string{"a.go", "b.go", "c.go", "d.go", "e.go"} {
+		if err := usage.collect(filename, []byte(sources[filename])); err != nil {
+			t.Fatalf("Failed to collect aliases from %s: %v", filename, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	usage.print(&buf, true)
+
+	expected := `! gopkg.in/yaml.v3 (2 different aliases)
+    (none) (2 file(s))
+      d.go
+      e.go
+    yaml (1 file(s))
+      c.go
+! k8s.io/api/core/v1 (2 different aliases)
+    corev1 (2 file(s))
+      a.go
+      b.go
+    (none) (1 file(s))
+      c.go
+! sigs.k8s.io/controller-runtime (2 different aliases)
+    ctrl (1 file(s))
+      c.go
+    ctrlruntime (1 file(s))
+      b.go
+`
+
+	if buf.String() != expected {
+		t.Fatalf("Expected\n\n%s\n\nbut got\n\n%s", expected, buf.String())
+	}
+
+	existing := []gimps.AliasRule{{
+		Name:       "controller-runtime",
+		Expression: "^sigs.k8s.io/controller-runtime$",
+		Alias:      "ctrlruntime",
+	}}
+
+	expectedSuggestions := []gimps.AliasRule{
+		{
+			Name:       "gopkg-in-yaml-v3",
+			Type:       gimps.AliasRuleTypeUnalias,
+			Expression: `^gopkg\.in/yaml\.v3$`,
+		},
+		{
+			Name:       "k8s-io-api-core-v1",
+			Expression: `^k8s\.io/api/core/v1$`,
+			Alias:      "corev1",
+		},
+	}
+
+	suggestions := usage.suggestRules(existing)
+	if len(suggestions) != len(expectedSuggestions) {
+		t.Fatalf("Expected %d suggestions, but got %+v", len(expectedSuggestions), suggestions)
+	}
+
+	for i, suggestion := range suggestions {
+		if suggestion != expectedSuggestions[i] {
+			t.Errorf("Expected suggestion %d to be %+v, but got %+v", i, expectedSuggestions[i], suggestion)
+		}
+	}
+}
@@ -4,7 +4,10 @@
 package main
 
 import (
+	"bytes"
 	"errors"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
 
@@ -60,20 +63,55 @@ func loadConfiguration(filename string, moduleRoot string) (*Config, error) {
 		// file exists, continue loading as normal
 	}
 
-	f, err := os.Open(filename)
+	content, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
 
-	c := &Config{}
-	if err := yaml.NewDecoder(f).Decode(c); err != nil {
+	c, warnings, err := decodeConfiguration(filename, content)
+	if err != nil {
 		return nil, err
 	}
 
+	for _, warning := range warnings {
+		log.Printf("Warning: %s", warning)
+	}
+
 	return defaultConfig(c), nil
 }
 
+// decodeConfiguration strictly decodes the YAML, i.e. unknown fields lead to
+// an error, and then validates the configuration. Problems that do not
+// prevent using the configuration are returned as warnings.
+func decodeConfiguration(filename string, content []byte) (*Config, []string, error) {
+	c := &Config{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(c); err != nil {
+		// an empty file is a valid, empty configuration
+		if errors.Is(err, io.EOF) {
+			return c, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	// decode again to get positional information for validation errors
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, nil, err
+	}
+
+	warnings, err := validateConfiguration(filename, c, &root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c, warnings, nil
+}
+
 func defaultConfig(c *Config) *Config {
 	if c.Exclude == nil {
 		c.Exclude = defaultExcludes
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// unifiedDiff returns a unified diff between the original and the formatted
+// source code. The file headers use the usual "a/" and "b/" prefixes, so the
+// output can be applied using `git apply` or `patch -p1`. relPath must be
+// relative to the module root.
+func unifiedDiff(relPath string, original []byte, formatted []byte) (string, error) {
+	relPath = filepath.ToSlash(relPath)
+
+	diff := difflib.UnifiedDiff{
+		A:        splitLines(original),
+		B:        splitLines(formatted),
+		FromFile: "a/" + relPath,
+		ToFile:   "b/" + relPath,
+		Context:  3,
+	}
+
+	return difflib.GetUnifiedDiffString(diff)
+}
+
+// noNewlineMarker follows the last line of a file that does not end with a
+// newline, just like in the output of `git diff`.
+const noNewlineMarker = "\n\\ No newline at end of file\n"
+
+// splitLines splits the source into lines, each retaining its trailing newline.
+// Unlike difflib.SplitLines, this does not produce an additional empty line
+// if the source ends with a newline, which would otherwise end up as bogus
+// context in the diff. If the source does not end with a newline, the marker
+// is appended to the last line. This makes the line differ from the same line
+// with a newline, and difflib prints the marker right after the line.
+func splitLines(source []byte) []string {
+	if len(source) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(source), "\n")
+
+	last := len(lines) - 1
+	if lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += noNewlineMarker
+	}
+
+	return lines
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	original := `package main
+
+import (
+	"os"
+	"fmt"
+)
+`
+
+	formatted := `package main
+
+import (
+	"fmt"
+	"os"
+)
+`
+
+	expected := `--- a/pkg/foo/main.go
++++ b/pkg/foo/main.go
+@@ -1,6 +1,6 @@
+ package main
+ 
+ import (
++	"fmt"
+ 	"os"
+-	"fmt"
+ )
+`
+
+	diff, err := unifiedDiff("pkg/foo/main.go", []byte(original), []byte(formatted))
+	if err != nil {
+		t.Fatalf("should not have errored, but got %v", err)
+	}
+
+	if diff != expected {
+		t.Errorf("Expected\n\n%s\n\nbut got\n\n%s", expected, diff)
+	}
+}
+
+func TestUnifiedDiffWithoutTrailingNewline(t *testing.T) {
+	testcases := []struct {
+		name      string
+		original  string
+		formatted string
+		expected  string
+	}{
+		{
+			name:      "only the trailing newline is added",
+			original:  "package main\n\nfunc main() {}",
+			formatted: "package main\n\nfunc main() {}\n",
+			expected: `--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+ 
+-func main() {}
+\ No newline at end of file
++func main() {}
+`,
+		},
+		{
+			name:      "imports are changed as well",
+			original:  "package main\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n\nfunc main() { fmt.Println(os.Args) }",
+			formatted: "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() { fmt.Println(os.Args) }\n",
+			expected: `--- a/main.go
++++ b/main.go
+@@ -1,8 +1,8 @@
+ package main
+ 
+ import (
++	"fmt"
+ 	"os"
+-	"fmt"
+ )
+ 
+-func main() { fmt.Println(os.Args) }
+\ No newline at end of file
++func main() { fmt.Println(os.Args) }
+`,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := unifiedDiff("main.go", []byte(tt.original), []byte(tt.formatted))
+			if err != nil {
+				t.Fatalf("should not have errored, but got %v", err)
+			}
+
+			if diff != tt.expected {
+				t.Errorf("Expected\n\n%s\n\nbut got\n\n%s", tt.expected, diff)
+			}
+		})
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	doublestarx "github.com/bmatcuk/doublestar/v4"
@@ -22,16 +24,19 @@ import (
 // exactly one element, otherwise the directory is scanned recursively.
 // Note that if start is a file, the skip rules are not evaluated. This allows
 // users to force-format an otherwise skipped file.
-func listFiles(start string, moduleRoot string, skips []string) ([]string, error) {
+// The second return value contains all Go files that were skipped because of
+// the skip rules; files in skipped directories are not included.
+func listFiles(start string, moduleRoot string, skips []string) ([]string, []string, error) {
 	result := []string{}
+	skipped := []string{}
 
 	info, err := os.Stat(start)
 	if err != nil {
-		return nil, fmt.Errorf("invalid file: %v", err)
+		return nil, nil, fmt.Errorf("invalid file: %v", err)
 	}
 
 	if !info.IsDir() {
-		return []string{start}, nil
+		return []string{start}, skipped, nil
 	}
 
 	err = filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
@@ -44,25 +49,42 @@ func listFiles(start string, moduleRoot string, skips []string) ([]string, error
 			return fmt.Errorf("invalid file: %v", err)
 		}
 
+		isGoFile := !d.IsDir() && strings.HasSuffix(path, ".go")
+
 		if isSkipped(relPath, skips) {
 			if d.IsDir() {
 				return filepath.SkipDir
-			} else {
-				return nil
 			}
+
+			if isGoFile {
+				skipped = append(skipped, path)
+			}
+
+			return nil
 		}
 
-		if !d.IsDir() && strings.HasSuffix(path, ".go") {
+		if isGoFile {
 			result = append(result, path)
 		}
 
 		return nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return result, nil
+	return result, skipped, nil
+}
+
+// sortWalkOrder sorts the paths in the order filepath.WalkDir visits them,
+// i.e. by comparing them element by element.
+func sortWalkOrder(paths []string) {
+	sort.SliceStable(paths, func(i, j int) bool {
+		a := strings.Split(filepath.ToSlash(paths[i]), "/")
+		b := strings.Split(filepath.ToSlash(paths[j]), "/")
+
+		return slices.Compare(a, b) < 0
+	})
 }
 
 func isSkipped(relPath string, skips []string) bool {
@@ -5,6 +5,9 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -148,3 +151,91 @@ func runGeneratedCodeTest(code string, expected bool) func(t *testing.T) {
 		}
 	}
 }
+
+func TestListFiles(t *testing.T) {
+	root := t.TempDir()
+
+	files := []string{
+		"main.go",
+		"README.md",
+		"pkg/foo/foo.go",
+		"pkg/foo/zz_generated.deepcopy.go",
+		"vendor/example.com/bar/bar.go",
+	}
+
+	for _, file := range files {
+		fullPath := filepath.Join(root, file)
+
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(fullPath, []byte("package foo\n"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	found, skipped, err := listFiles(root, root, defaultExcludes)
+	if err != nil {
+		t.Fatalf("should not have errored, but got %v", err)
+	}
+
+	expectedFound := []string{
+		filepath.Join(root, "main.go"),
+		filepath.Join(root, "pkg/foo/foo.go"),
+	}
+
+	if !slices.Equal(found, expectedFound) {
+		t.Errorf("Expected files %v, but got %v", expectedFound, found)
+	}
+
+	// files in skipped directories are not reported
+	expectedSkipped := []string{
+		filepath.Join(root, "pkg/foo/zz_generated.deepcopy.go"),
+	}
+
+	if !slices.Equal(skipped, expectedSkipped) {
+		t.Errorf("Expected skipped files %v, but got %v", expectedSkipped, skipped)
+	}
+}
+
+func TestSortWalkOrder(t *testing.T) {
+	root := t.TempDir()
+
+	files := []string{
+		"a.go",
+		"a/b.go",
+		"a/zz_generated.go",
+		"a-b/c.go",
+		"b.go",
+	}
+
+	for _, file := range files {
+		fullPath := filepath.Join(root, file)
+
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(fullPath, []byte("package foo\n"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	found, skipped, err := listFiles(root, root, []string{"**/zz_generated.go", "b.go"})
+	if err != nil {
+		t.Fatalf("should not have errored, but got %v", err)
+	}
+
+	merged := append(found, skipped...)
+	sortWalkOrder(merged)
+
+	expected := []string{}
+	for _, file := range []string{"a/b.go", "a/zz_generated.go", "a-b/c.go", "a.go", "b.go"} {
+		expected = append(expected, filepath.Join(root, file))
+	}
+
+	if !slices.Equal(merged, expected) {
+		t.Errorf("Expected files %v, but got %v", expected, merged)
+	}
+}
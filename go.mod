@@ -7,16 +7,16 @@ toolchain go1.23.4
 require (
 	github.com/bmatcuk/doublestar/v4 v4.8.1
 	github.com/incu6us/goimports-reviser/v3 v3.8.2
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/spf13/pflag v1.0.6
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/mod v0.23.0
+	golang.org/x/tools v0.30.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/kr/text v0.2.0 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/mod v0.23.0 // indirect
 	golang.org/x/sync v0.11.0 // indirect
-	golang.org/x/tools v0.30.0 // indirect
 )
@@ -4,11 +4,15 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 
 	"github.com/incu6us/goimports-reviser/v3/pkg/module"
@@ -17,6 +21,16 @@ import (
 	"go.xrstf.de/gimps/pkg/gimps"
 )
 
+const (
+	// exitCodeNeedsFormatting is used in --check mode when at least one
+	// file is not properly formatted.
+	exitCodeNeedsFormatting = 1
+
+	// exitCodeError is used whenever gimps could not do its job, e.g.
+	// because of an invalid configuration or unparseable source code.
+	exitCodeError = 2
+)
+
 // These variables get set by ldflags during compilation.
 var (
 	BuildTag    string
@@ -40,17 +54,41 @@ func printVersion() {
 	}
 }
 
+// fatalf is like log.Fatalf, but uses the dedicated error exit code, so that
+// errors can be distinguished from unformatted files in --check mode.
+func fatalf(format string, v ...any) {
+	log.Printf(format, v...)
+	os.Exit(exitCodeError)
+}
+
 func main() {
 	configFile := ""
+	check := false
+	diff := false
 	dryRun := false
 	showVersion := false
 	stdout := false
 	verbose := false
+	outputFormat := outputFormatText
+	jobs := runtime.NumCPU()
+	stdinFilename := ""
+
+	// "gimps aliases" is a subcommand with its own flags; a directory called
+	// "aliases" can still be formatted using "gimps ./aliases" or "gimps -- aliases"
+	if len(os.Args) > 1 && os.Args[1] == "aliases" {
+		runAliases(os.Args[2:])
+		return
+	}
 
 	pflag.StringVarP(&configFile, "config", "c", configFile, "Path to the config file (mandatory).")
 	pflag.BoolVarP(&stdout, "stdout", "s", stdout, "Print output to stdout instead of updating the source file(s).")
 	pflag.BoolVarP(&dryRun, "dry-run", "d", dryRun, "Do not update files.")
+	pflag.BoolVarP(&diff, "diff", "D", diff, "Do not update files, but print a unified diff for each unformatted file.")
+	pflag.BoolVarP(&check, "check", "C", check, "Do not update files, but list all unformatted files and exit with code 1 if there are any.")
 	pflag.BoolVarP(&verbose, "verbose", "v", verbose, "List all instead of just changed files.")
+	pflag.StringVarP(&outputFormat, "output", "o", outputFormat, fmt.Sprintf("Output format, one of %q or %q.", outputFormatText, outputFormatJSON))
+	pflag.IntVarP(&jobs, "jobs", "j", jobs, "Number of files to process in parallel.")
+	pflag.StringVar(&stdinFilename, "stdin-filename", stdinFilename, "Read the source code from stdin and print the result to stdout. The filename is used to find the module, apply exclude rules and load dependencies.")
 	pflag.BoolVarP(&showVersion, "version", "V", showVersion, "Show version and exit.")
 	pflag.Parse()
 
@@ -59,13 +97,49 @@ func main() {
 		return
 	}
 
-	if pflag.NArg() == 0 {
-		log.Fatal("Usage: gimps [--stdout] [--dry-run] [--config=(autodetect)] FILE_OR_DIRECTORY[, ...]")
+	stdinFilename, err := stdinInput(pflag.Args(), stdinFilename, check, diff, outputFormat)
+	if err != nil {
+		fatalf("Invalid arguments: %v.", err)
+	}
+
+	readStdin := stdinFilename != ""
+
+	if !readStdin && pflag.NArg() == 0 {
+		log.Printf("Usage: gimps [--stdout] [--dry-run] [--check] [--diff] [--config=(autodetect)] [--] FILE_OR_DIRECTORY[, ...]")
+		log.Printf("       gimps [--config=(autodetect)] [--stdin-filename=FILE] -")
+		log.Printf("       gimps aliases [--config=(autodetect)] [--inconsistent] [FILE_OR_DIRECTORY, ...]")
+		os.Exit(exitCodeError)
+	}
+
+	if check && stdout {
+		fatalf("--check and --stdout cannot be combined.")
+	}
+
+	if diff && stdout {
+		fatalf("--diff and --stdout cannot be combined.")
+	}
+
+	if outputFormat != outputFormatText && outputFormat != outputFormatJSON {
+		fatalf("Invalid --output %q, must be %q or %q.", outputFormat, outputFormatText, outputFormatJSON)
+	}
+
+	if jobs < 1 {
+		fatalf("--jobs must be at least 1.")
+	}
+
+	jsonOutput := outputFormat == outputFormatJSON
+	if jsonOutput && (stdout || diff) {
+		fatalf("--output=%s cannot be combined with --stdout or --diff.", outputFormatJSON)
+	}
+
+	args := pflag.Args()
+	if readStdin {
+		args = []string{stdinFilename}
 	}
 
-	inputs, err := cleanupArgs(pflag.Args())
+	inputs, err := cleanupArgs(args)
 	if err != nil {
-		log.Fatalf("Invalid arguments: %v.", err)
+		fatalf("Invalid arguments: %v.", err)
 	}
 
 	// to auto-detect the .gimps.yaml, we need to find the go.mod; this can fail in
@@ -74,76 +148,386 @@ func main() {
 
 	config, err := loadConfiguration(configFile, modRoot)
 	if err != nil {
-		log.Fatalf("Failed to load -config file %q: %v", configFile, err)
+		fatalf("Failed to load -config file %q: %v", configFile, err)
 	}
 
 	if config.ProjectName == "" {
 		if modRootErr != nil {
-			log.Fatalf("Failed to auto-detect module root: %v", err)
+			fatalf("Failed to auto-detect module root: %v", err)
 		}
 
 		modName, err := module.Name(modRoot)
 		if err != nil {
-			log.Fatalf("Failed to auto-detect project name based on the first given file (%q): %v", inputs[0], err)
+			fatalf("Failed to auto-detect project name based on the first given file (%q): %v", inputs[0], err)
 		}
 
 		config.ProjectName = modName
 	}
 
-	aliaser, err := gimps.NewAliaser(config.ProjectName, config.AliasRules)
+	aliaser, err := gimps.NewAliaser(&config.Config)
 	if err != nil {
-		log.Fatalf("Failed to initialize aliaser: %v", err)
+		fatalf("Failed to initialize aliaser: %v", err)
+	}
+
+	if readStdin {
+		if err := formatStdin(config, aliaser, modRoot, inputs[0], os.Stdin, os.Stdout); err != nil {
+			fatalf("Failed to process stdin: %v", err)
+		}
+
+		return
+	}
+
+	// in check mode, processing errors do not abort the run, so that
+	// all broken and unformatted files can be listed at once
+	keepGoing := check || jsonOutput
+	summary := &runSummary{}
+
+	// alias conflicts are summarized at the end of the run
+	conflicts := []string{}
+
+	report := &jsonReport{
+		Files: []fileReport{},
 	}
 
+	// collect all files first, so they can be processed in parallel; excluded
+	// files are only needed for the report, where they are listed in the same
+	// order as all other files
+	filenames := []string{}
+	excludedFiles := map[string]bool{}
+
 	for _, input := range inputs {
-		filenames, err := listFiles(input, modRoot, config.Exclude)
+		found, excluded, err := listFiles(input, modRoot, config.Exclude)
 		if err != nil {
-			log.Fatalf("Failed to process %q: %v", input, err)
+			fatalf("Failed to process %q: %v", input, err)
 		}
 
-		for _, filename := range filenames {
-			if *config.DetectGeneratedFiles {
-				generated, err := isGeneratedFile(filename)
-				if err != nil {
-					log.Fatalf("Cannot check if file %q is generated: %v", filename, err)
-				}
+		if jsonOutput {
+			for _, filename := range excluded {
+				excludedFiles[filename] = true
+			}
 
-				if generated {
-					continue
-				}
+			found = append(found, excluded...)
+			sortWalkOrder(found)
+		}
+
+		filenames = append(filenames, found...)
+	}
+
+	for res := range processFiles(config, aliaser, modRoot, filenames, excludedFiles, jobs) {
+		filename := res.filename
+
+		if verbose && res.skipped == "" {
+			log.Printf("> %s", res.relPath)
+		}
+
+		if jsonOutput {
+			report.Add(res)
+		}
+
+		summary.Add(res)
+
+		if res.err != nil {
+			if !keepGoing {
+				fatalf("Failed to process %q: %v", filename, res.err)
 			}
 
-			relPath, err := filepath.Rel(modRoot, filename)
-			if err != nil {
-				log.Fatalf("This should never happen, could not determine relative path: %v", err)
+			log.Printf("Failed to process %q: %v", filename, res.err)
+			continue
+		}
+
+		if res.skipped != "" {
+			continue
+		}
+
+		result := res.result
+
+		for _, conflict := range result.Conflicts {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %s", res.relPath, conflict))
+		}
+
+		// violations are printed to stdout only in check mode, to not
+		// interfere with the formatted code or diffs
+		for _, violation := range result.Violations {
+			switch {
+			case jsonOutput:
+			case check:
+				fmt.Printf("%s:%d: %s\n", res.relPath, violation.Line, violation.Message)
+			default:
+				log.Printf("%s:%d: %s", res.relPath, violation.Line, violation.Message)
 			}
+		}
+
+		switch {
+		case stdout:
+			fmt.Print(string(result.Output))
+
+		case diff:
+			if result.Changed {
+				// Execute does not return the original content
+				originalContent, err := os.ReadFile(filename)
+				if err != nil {
+					fatalf("Failed to read %q: %v", filename, err)
+				}
 
-			if verbose {
-				log.Printf("> %s", relPath)
+				patch, err := unifiedDiff(res.relPath, originalContent, result.Output)
+				if err != nil {
+					fatalf("Failed to create diff for %q: %v", filename, err)
+				}
+
+				fmt.Print(patch)
 			}
 
-			formattedOutput, hasChange, err := gimps.Execute(&config.Config, filename, aliaser)
-			if err != nil {
-				log.Fatalf("Failed to process %q: %v", filename, err)
+		case check:
+			if result.Changed {
+				if !jsonOutput {
+					fmt.Println(res.relPath)
+				}
 			}
 
-			if stdout {
-				fmt.Print(string(formattedOutput))
-			} else if hasChange {
+		case result.Changed:
+			if !jsonOutput {
 				if verbose {
-					log.Printf("! %s", relPath)
+					log.Printf("! %s", res.relPath)
 				} else {
-					log.Printf("Fixed %s", relPath)
+					log.Printf("Fixed %s", res.relPath)
 				}
+			}
 
-				if !dryRun {
-					if err := os.WriteFile(filename, formattedOutput, 0644); err != nil {
-						log.Fatalf("Failed to write fixed result to file %q: %v", filename, err)
-					}
+			if !dryRun {
+				if err := os.WriteFile(filename, result.Output, 0644); err != nil {
+					fatalf("Failed to write fixed result to file %q: %v", filename, err)
 				}
 			}
 		}
 	}
+
+	if len(conflicts) > 0 && !jsonOutput {
+		log.Printf("Resolved %d alias conflict(s):", len(conflicts))
+		for _, conflict := range conflicts {
+			log.Printf("  %s", conflict)
+		}
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(report); err != nil {
+			fatalf("Failed to encode report: %v", err)
+		}
+	}
+
+	if summary.failed > 0 {
+		log.Printf("Failed to process %d file(s).", summary.failed)
+	} else if check {
+		if summary.unformatted > 0 {
+			log.Printf("%d file(s) need to be formatted.", summary.unformatted)
+		}
+
+		if summary.violations > 0 {
+			log.Printf("%d import(s) violate the alias rules.", summary.violations)
+		}
+	}
+
+	if exitCode := summary.ExitCode(check); exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// runSummary counts the outcome of all processed files.
+type runSummary struct {
+	unformatted int
+	violations  int
+	failed      int
+}
+
+// Add counts the result of a single file.
+func (s *runSummary) Add(res fileResult) {
+	switch {
+	case res.err != nil:
+		s.failed++
+	case res.skipped != "":
+	default:
+		s.violations += len(res.result.Violations)
+		if res.result.Changed {
+			s.unformatted++
+		}
+	}
+}
+
+// ExitCode returns the exit code of the run. Errors always take precedence,
+// unformatted files and alias rule violations only fail the run in --check
+// mode.
+func (s *runSummary) ExitCode(check bool) int {
+	if s.failed > 0 {
+		return exitCodeError
+	}
+
+	if check && (s.unformatted > 0 || s.violations > 0) {
+		return exitCodeNeedsFormatting
+	}
+
+	return 0
+}
+
+const (
+	skipReasonExcluded  = "excluded"
+	skipReasonGenerated = "generated"
+)
+
+// fileResult is the outcome of processing a single file.
+type fileResult struct {
+	filename string
+	relPath  string
+	skipped  string
+	result   *gimps.Result
+	err      error
+}
+
+// processFiles formats all given files using a pool of workers. The results
+// are sent to the returned channel in the same order as the files were given,
+// regardless of which worker finished first, so that the output is stable.
+// Excluded files are not processed, but still reported as skipped.
+func processFiles(config *Config, aliaser *gimps.Aliaser, modRoot string, filenames []string, excluded map[string]bool, workers int) <-chan fileResult {
+	pending := make([]chan fileResult, len(filenames))
+	for i := range pending {
+		pending[i] = make(chan fileResult, 1)
+	}
+
+	jobs := make(chan int)
+	go func() {
+		for i := range filenames {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	for range workers {
+		go func() {
+			for i := range jobs {
+				pending[i] <- processFile(config, aliaser, modRoot, filenames[i], excluded[filenames[i]])
+			}
+		}()
+	}
+
+	results := make(chan fileResult)
+	go func() {
+		for _, result := range pending {
+			results <- <-result
+		}
+		close(results)
+	}()
+
+	return results
+}
+
+func processFile(config *Config, aliaser *gimps.Aliaser, modRoot string, filename string, excluded bool) fileResult {
+	res := fileResult{
+		filename: filename,
+		relPath:  mustRelPath(modRoot, filename),
+	}
+
+	if excluded {
+		res.skipped = skipReasonExcluded
+		return res
+	}
+
+	if *config.DetectGeneratedFiles {
+		generated, err := isGeneratedFile(filename)
+		if err != nil {
+			res.err = fmt.Errorf("cannot check if file is generated: %w", err)
+			return res
+		}
+
+		if generated {
+			res.skipped = skipReasonGenerated
+			return res
+		}
+	}
+
+	res.result, res.err = gimps.Execute(&config.Config, filename, aliaser)
+
+	return res
+}
+
+// stdinInput returns the filename to use for source code read from stdin,
+// or an empty string if files and directories are given instead. "gimps -"
+// pretends the code is a file in the current directory.
+func stdinInput(args []string, stdinFilename string, check bool, diff bool, outputFormat string) (string, error) {
+	if slices.Contains(args, "-") && stdinFilename == "" {
+		stdinFilename = "stdin.go"
+	}
+
+	if stdinFilename == "" {
+		return "", nil
+	}
+
+	if len(args) > 1 || (len(args) == 1 && args[0] != "-") {
+		return "", errors.New("no further files or directories can be given when reading from stdin")
+	}
+
+	if check || diff || outputFormat != outputFormatText {
+		return "", errors.New("reading from stdin cannot be combined with --check, --diff or --output")
+	}
+
+	return stdinFilename, nil
+}
+
+// formatStdin formats the source code read from stdin and prints the result
+// to stdout. The file does not need to exist, its name is only used to apply
+// the exclude rules and to load dependencies. Excluded and generated code is
+// printed unchanged.
+func formatStdin(config *Config, aliaser *gimps.Aliaser, modRoot string, filename string, stdin io.Reader, stdout io.Writer) error {
+	source, err := io.ReadAll(stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+
+	skipped := isSkipped(mustRelPath(modRoot, filename), config.Exclude)
+
+	if !skipped && *config.DetectGeneratedFiles {
+		skipped, err = isGeneratedCode(source)
+		if err != nil {
+			return fmt.Errorf("cannot check if code is generated: %w", err)
+		}
+	}
+
+	output := source
+
+	if !skipped {
+		result, err := gimps.Format(&config.Config, filename, source, aliaser)
+		if err != nil {
+			return err
+		}
+
+		output = result.Output
+
+		for _, violation := range result.Violations {
+			log.Printf("%s:%d: %s", filename, violation.Line, violation.Message)
+		}
+
+		for _, conflict := range result.Conflicts {
+			log.Printf("Resolved alias conflict in %s: %s", filename, conflict)
+		}
+	}
+
+	_, err = stdout.Write(output)
+
+	return err
+}
+
+func mustRelPath(modRoot string, filename string) string {
+	// without a module, there is nothing to be relative to
+	if modRoot == "" {
+		return filename
+	}
+
+	relPath, err := filepath.Rel(modRoot, filename)
+	if err != nil {
+		fatalf("This should never happen, could not determine relative path: %v", err)
+	}
+
+	return relPath
 }
 
 // cleanupArgs removes duplicates and turns every argument into an absolute
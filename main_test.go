@@ -0,0 +1,304 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.xrstf.de/gimps/pkg/gimps"
+)
+
+const (
+	formattedSource = `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Println(os.Args)
+}
+`
+
+	unformattedSource = `package main
+
+import "os"
+import "fmt"
+
+func main() {
+	fmt.Println(os.Args)
+}
+`
+
+	brokenSource = `package main
+
+import (
+`
+
+	forbiddenAliasSource = `package main
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+var _ = v1.Pod{}
+`
+)
+
+// newTestSetup returns a config with in-memory package name resolution and
+// writes the given files into a temporary directory.
+func newTestSetup(t *testing.T, files map[string]string) (*Config, *gimps.Aliaser, string) {
+	t.Helper()
+
+	config := defaultConfig(&Config{
+		Config: gimps.Config{
+			ProjectName: "example.com/test",
+			ForbiddenAliases: []gimps.ForbiddenAlias{{
+				Name:  "no-bare-versions",
+				Alias: `^v[0-9]+$`,
+			}},
+		},
+	})
+
+	aliaser, err := gimps.NewAliaserWithResolver(&config.Config, gimps.PackageNames{})
+	if err != nil {
+		t.Fatalf("Failed to create aliaser: %v", err)
+	}
+
+	dir := t.TempDir()
+	for filename, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, filename), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", filename, err)
+		}
+	}
+
+	return config, aliaser, dir
+}
+
+func TestRunSummaryExitCode(t *testing.T) {
+	testcases := []struct {
+		name          string
+		files         map[string]string
+		check         bool
+		expectedCode  int
+		expectedFails int
+	}{
+		{
+			name:         "all files formatted",
+			files:        map[string]string{"a.go": formattedSource, "b.go": formattedSource},
+			check:        true,
+			expectedCode: 0,
+		},
+		{
+			name:         "unformatted file in check mode",
+			files:        map[string]string{"a.go": formattedSource, "b.go": unformattedSource},
+			check:        true,
+			expectedCode: exitCodeNeedsFormatting,
+		},
+		{
+			name:         "unformatted file without check mode",
+			files:        map[string]string{"a.go": formattedSource, "b.go": unformattedSource},
+			check:        false,
+			expectedCode: 0,
+		},
+		{
+			name:         "alias rule violation in check mode",
+			files:        map[string]string{"a.go": forbiddenAliasSource},
+			check:        true,
+			expectedCode: exitCodeNeedsFormatting,
+		},
+		{
+			name:          "one broken file among good ones",
+			files:         map[string]string{"a.go": formattedSource, "b.go": brokenSource, "c.go": unformattedSource},
+			check:         true,
+			expectedCode:  exitCodeError,
+			expectedFails: 1,
+		},
+		{
+			name:          "broken file without check mode",
+			files:         map[string]string{"a.go": formattedSource, "b.go": brokenSource},
+			check:         false,
+			expectedCode:  exitCodeError,
+			expectedFails: 1,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			config, aliaser, dir := newTestSetup(t, testcase.files)
+
+			filenames, _, err := listFiles(dir, dir, config.Exclude)
+			if err != nil {
+				t.Fatalf("Failed to list files: %v", err)
+			}
+
+			summary := &runSummary{}
+			for res := range processFiles(config, aliaser, dir, filenames, nil, 2) {
+				summary.Add(res)
+			}
+
+			if summary.failed != testcase.expectedFails {
+				t.Errorf("Expected %d failed file(s), got %d.", testcase.expectedFails, summary.failed)
+			}
+
+			if code := summary.ExitCode(testcase.check); code != testcase.expectedCode {
+				t.Errorf("Expected exit code %d, got %d.", testcase.expectedCode, code)
+			}
+		})
+	}
+}
+
+func TestStdinInput(t *testing.T) {
+	testcases := []struct {
+		name          string
+		args          []string
+		stdinFilename string
+		check         bool
+		diff          bool
+		outputFormat  string
+		expected      string
+		expectedErr   bool
+	}{
+		{
+			name:     "files only",
+			args:     []string{"main.go"},
+			expected: "",
+		},
+		{
+			name:     "dash",
+			args:     []string{"-"},
+			expected: "stdin.go",
+		},
+		{
+			name:          "stdin filename",
+			stdinFilename: "pkg/foo/foo.go",
+			expected:      "pkg/foo/foo.go",
+		},
+		{
+			name:          "stdin filename with dash",
+			args:          []string{"-"},
+			stdinFilename: "pkg/foo/foo.go",
+			expected:      "pkg/foo/foo.go",
+		},
+		{
+			name:        "dash with check",
+			args:        []string{"-"},
+			check:       true,
+			expectedErr: true,
+		},
+		{
+			name:        "dash with diff",
+			args:        []string{"-"},
+			diff:        true,
+			expectedErr: true,
+		},
+		{
+			name:         "dash with JSON output",
+			args:         []string{"-"},
+			outputFormat: outputFormatJSON,
+			expectedErr:  true,
+		},
+		{
+			name:        "dash with further inputs",
+			args:        []string{"-", "main.go"},
+			expectedErr: true,
+		},
+		{
+			name:          "stdin filename with further inputs",
+			args:          []string{"main.go"},
+			stdinFilename: "pkg/foo/foo.go",
+			expectedErr:   true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			outputFormat := testcase.outputFormat
+			if outputFormat == "" {
+				outputFormat = outputFormatText
+			}
+
+			filename, err := stdinInput(testcase.args, testcase.stdinFilename, testcase.check, testcase.diff, outputFormat)
+			if testcase.expectedErr {
+				if err == nil {
+					t.Fatalf("Expected an error, but got filename %q.", filename)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+
+			if filename != testcase.expected {
+				t.Errorf("Expected filename %q, got %q.", testcase.expected, filename)
+			}
+		})
+	}
+}
+
+func TestFormatStdin(t *testing.T) {
+	generatedSource := "// Code generated by hand. DO NOT EDIT.\n\n" + unformattedSource
+
+	testcases := []struct {
+		name      string
+		filename  string
+		source    string
+		unchanged bool
+	}{
+		{
+			name:     "regular file",
+			filename: "main.go",
+			source:   unformattedSource,
+		},
+		{
+			name:      "excluded file",
+			filename:  "zz_generated.deepcopy.go",
+			source:    unformattedSource,
+			unchanged: true,
+		},
+		{
+			name:      "generated code",
+			filename:  "main.go",
+			source:    generatedSource,
+			unchanged: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			config, aliaser, dir := newTestSetup(t, map[string]string{testcase.filename: testcase.source})
+			filename := filepath.Join(dir, testcase.filename)
+
+			var stdout bytes.Buffer
+			if err := formatStdin(config, aliaser, dir, filename, strings.NewReader(testcase.source), &stdout); err != nil {
+				t.Fatalf("Failed to format stdin: %v", err)
+			}
+
+			expected := testcase.source
+			if !testcase.unchanged {
+				// stdin must be formatted exactly like the file itself
+				res := processFile(config, aliaser, dir, filename, false)
+				if res.err != nil {
+					t.Fatalf("Failed to process file: %v", res.err)
+				}
+
+				expected = string(res.result.Output)
+				if expected == testcase.source {
+					t.Fatal("Test source should need formatting.")
+				}
+			}
+
+			if stdout.String() != expected {
+				t.Errorf("Expected\n%s\nbut got\n%s", expected, stdout.String())
+			}
+		})
+	}
+}
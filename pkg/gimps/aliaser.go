@@ -4,57 +4,219 @@
 package gimps
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/token"
+	"go/types"
+	"path"
 	"regexp"
-
-	"github.com/incu6us/goimports-reviser/v3/pkg/astutil"
+	"slices"
+	"text/template"
 )
 
 type Aliaser struct {
-	projectName string
-	rules       []AliasRule
-	deps        DependencyCache
+	projectName      string
+	rules            []AliasRule
+	requiredAliases  []AliasRule
+	forbiddenAliases []ForbiddenAlias
+	setVersionAlias  bool
+	typeCheck        bool
+	expandDotImports bool
+	aliasConflicts   string
+	deps             PackageNameResolver
+	loader           *DependencyCache
+	classifier       *Classifier
+	// config is the config the aliaser has been created for.
+	config *Config
 }
 
 type AliasRule struct {
-	Name       string         `yaml:"name"`
+	Name string `yaml:"name"`
+	// Type is either AliasRuleTypeAlias (the default) or AliasRuleTypeUnalias.
+	Type       string         `yaml:"type,omitempty"`
 	Expression string         `yaml:"expr"`
 	regexp     *regexp.Regexp `yaml:"-"`
-	Alias      string         `yaml:"alias"`
+	// Alias is either a regexp replacement (like "$1$2") or, if it contains
+	// "{{", a text/template.
+	Alias    string             `yaml:"alias,omitempty"`
+	template *template.Template `yaml:"-"`
+}
+
+const (
+	// AliasRuleTypeAlias rules set the alias of matching imports.
+	AliasRuleTypeAlias = "alias"
+
+	// AliasRuleTypeUnalias rules remove the alias from matching imports,
+	// so that they are referred to by their package name.
+	AliasRuleTypeUnalias = "unalias"
+)
+
+// NewAliaser returns an aliaser that determines package names by loading
+// the package dependencies of each processed file from disk.
+func NewAliaser(config *Config) (*Aliaser, error) {
+	return NewAliaserWithResolver(config, NewDependencyCache())
+}
+
+// NewAliaserWithResolver returns an aliaser that uses the given resolver to
+// determine package names. Use this with PackageNames to format code without
+// touching the filesystem. Sets based on the go.mod file and subgrouping by
+// module require the resolver to implement GoModResolver as well.
+// Type-checking and expanding dot imports require the resolver to be a
+// DependencyCache.
+func NewAliaserWithResolver(config *Config, resolver PackageNameResolver) (*Aliaser, error) {
+	loader, _ := resolver.(*DependencyCache)
+	if (config.TypeCheck || config.ExpandDotImports) && loader == nil {
+		return nil, errors.New("type-checking requires loading packages from disk, the resolver must be a DependencyCache")
+	}
+
+	aliasConflicts := config.AliasConflicts
+	if aliasConflicts == "" {
+		aliasConflicts = AliasConflictsFail
+	} else if !slices.Contains(AliasConflictStrategies, aliasConflicts) {
+		return nil, fmt.Errorf("invalid alias conflict strategy %q", aliasConflicts)
+	}
+
+	rules := config.AliasRules
+	if err := compileAliasRules(rules); err != nil {
+		return nil, err
+	}
+
+	requiredAliases := config.RequiredAliases
+	if err := compileAliasRules(requiredAliases); err != nil {
+		return nil, fmt.Errorf("invalid required aliases: %w", err)
+	}
+
+	forbiddenAliases := config.ForbiddenAliases
+	if err := compileForbiddenAliases(forbiddenAliases); err != nil {
+		return nil, err
+	}
+
+	// validate and compile the classification rules only once
+	configCopy := *config
+	setDefaults(&configCopy)
+
+	classifier, err := newConfigClassifier(&configCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Aliaser{
+		projectName:      config.ProjectName,
+		rules:            rules,
+		requiredAliases:  requiredAliases,
+		forbiddenAliases: forbiddenAliases,
+		setVersionAlias:  config.SetVersionAlias,
+		typeCheck:        config.TypeCheck,
+		expandDotImports: config.ExpandDotImports,
+		aliasConflicts:   aliasConflicts,
+		deps:             resolver,
+		loader:           loader,
+		classifier:       classifier,
+		config:           config,
+	}, nil
 }
 
-func NewAliaser(projectName string, rules []AliasRule) (*Aliaser, error) {
+func compileAliasRules(rules []AliasRule) error {
 	for i, rule := range rules {
 		expr, err := regexp.Compile(rule.Expression)
 		if err != nil {
-			return nil, fmt.Errorf("invalid expression in rule %d: %v", i+1, err)
+			return fmt.Errorf("invalid expression in rule %d: %v", i+1, err)
+		}
+
+		switch rule.Type {
+		case "", AliasRuleTypeAlias:
+		case AliasRuleTypeUnalias:
+			if rule.Alias != "" {
+				return fmt.Errorf("rule %d removes aliases and must not configure an alias", i+1)
+			}
+		default:
+			return fmt.Errorf("invalid type %q in rule %d", rule.Type, i+1)
 		}
 
 		rules[i].regexp = expr
+
+		if isAliasTemplate(rule.Alias) {
+			tpl, err := ParseAliasTemplate(rule.Name, rule.Alias)
+			if err != nil {
+				return fmt.Errorf("invalid alias template in rule %d: %v", i+1, err)
+			}
+
+			rules[i].template = tpl
+		}
 	}
 
-	return &Aliaser{
-		projectName: projectName,
-		rules:       rules,
-		deps:        NewDependencyCache(),
-	}, nil
+	return nil
 }
 
-var validAlias = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+var (
+	validAlias = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+	// versionedPackage matches import paths with a major version suffix, like
+	// "github.com/bmatcuk/doublestar/v4" or "gopkg.in/yaml.v3".
+	versionedPackage = regexp.MustCompile(`[/.]v[0-9]+$`)
+)
 
-func (a *Aliaser) RewriteFile(file *ast.File, filePath string, imports map[string]*importMetadata) error {
+// RewriteFile applies the alias rules to the given imports and renames all
+// references to the affected packages in the file. Conflicts between the new
+// aliases are resolved according to the configured strategy and returned.
+func (a *Aliaser) RewriteFile(fset *token.FileSet, file *ast.File, filePath string, imports map[string]*importMetadata) ([]AliasConflict, error) {
 	// do not waste time loading package dependencies
-	if len(a.rules) == 0 {
-		return nil
+	if len(a.rules) == 0 && !a.setVersionAlias && !a.expandDotImports {
+		return nil, nil
 	}
 
-	// map of old package name (can be alias) to new alias
-	aliasRenames := map[string]string{}
+	// imports whose alias has been changed by a rule
+	planned := map[*importMetadata]*plannedAlias{}
+
+	// version aliases and expanded dot imports do not require renaming
+	// identifiers (anymore), but still require to rebuild the imports
+	unchangedNames := 0
+
+	buildTags := parseBuildTags(file)
 
-	buildTags := astutil.ParseBuildTag(file)
+	// the type information is only determined when needed, but always
+	// before the file is modified
+	var typed *typedFile
+
+	if a.expandDotImports && hasDotImports(imports) {
+		var err error
+
+		typed, err = a.loader.typeCheck(file, filePath, buildTags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to type-check: %v", err)
+		}
+
+		expanded, err := expandDotImports(fset, file, typed, imports)
+		if err != nil {
+			return nil, err
+		}
+
+		unchangedNames += expanded
+	}
+
+	// packageNames caches the actual package names; if a name cannot
+	// be determined, it is treated as empty
+	packageNames := map[string]string{}
+	packageName := func(pkg string) string {
+		name, ok := packageNames[pkg]
+		if !ok {
+			name, _ = a.deps.GetPackageName(filePath, buildTags, pkg)
+			packageNames[pkg] = name
+		}
+
+		return name
+	}
+
+	effectiveName := func(metadata *importMetadata) string {
+		if metadata.Alias != "" {
+			return metadata.Alias
+		}
 
-	// process each of the file's imports
+		return packageName(metadata.Package)
+	}
+
+	// plan the new aliases for each of the file's imports
 	for imprt, metadata := range imports {
 		// find the first rule that applies
 		var rule *AliasRule
@@ -67,60 +229,88 @@ func (a *Aliaser) RewriteFile(file *ast.File, filePath string, imports map[strin
 
 		// no rule matched
 		if rule == nil {
+			if a.setVersionAlias && metadata.Alias == "" && versionedPackage.MatchString(metadata.Package) {
+				alias, err := a.versionAlias(filePath, buildTags, metadata.Package)
+				if err != nil {
+					return nil, err
+				}
+
+				if alias != "" {
+					metadata.Alias = alias
+					unchangedNames++
+				}
+			}
+
+			continue
+		}
+
+		if rule.Type == AliasRuleTypeUnalias {
+			// blank and dot imports do not introduce a name that could be removed
+			if metadata.Alias == "" || metadata.Alias == "_" || metadata.Alias == "." {
+				continue
+			}
+
+			if _, err := a.deps.GetPackageName(filePath, buildTags, metadata.Package); err != nil {
+				return nil, fmt.Errorf("invalid state: file imports %q, but: %w", metadata.Package, err)
+			}
+
+			planned[metadata] = &plannedAlias{rule: rule, previous: metadata.Alias}
+			metadata.Alias = ""
 			continue
 		}
 
 		// cannot rewrite dot imports, because without parsing the whole
 		// package, we cannot know what identifier resolves to the dot-imported
-		// package
-		oldAlias := metadata.Alias
-		if oldAlias == "." {
-			return fmt.Errorf("cannot rewrite, import %q matches rule %s but is a dot-import; dot-imports cannot be rewritten", imprt, rule.Name)
+		// package; use expandDotImports to turn them into regular imports first
+		if metadata.Alias == "." {
+			return nil, fmt.Errorf("cannot rewrite, import %q matches rule %s but is a dot-import; dot-imports cannot be rewritten", imprt, rule.Name)
 		}
 
 		// if there was no alias, the package was properly referred to by its name
-		if oldAlias == "" {
+		oldName := metadata.Alias
+		if oldName == "" {
 			// determine the actual names of packages, e.g. resolve
-			// "github.com/bmatcuk/doublestar/v4" to "doublestar";
-			// packageNames is a map of [path]=>[name]
-
+			// "github.com/bmatcuk/doublestar/v4" to "doublestar"
 			var err error
-			oldAlias, err = a.deps.GetPackageName(filePath, buildTags, metadata.Package)
+			oldName, err = a.deps.GetPackageName(filePath, buildTags, metadata.Package)
 			if err != nil {
-				return fmt.Errorf("invalid state: file imports %q, but: %v", metadata.Package, err)
+				return nil, fmt.Errorf("invalid state: file imports %q, but: %w", metadata.Package, err)
 			}
 		}
 
 		// generate new alias
-		newAlias := rule.regexp.ReplaceAllString(metadata.Package, rule.Alias)
+		newAlias, err := rule.generateAlias(metadata.Package, a.packageNameFunc(filePath, buildTags, metadata.Package))
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply rule %s to %q: %v", rule.Name, metadata.Package, err)
+		}
+
 		if newAlias == "" {
-			return fmt.Errorf("applying rule %s to %q leads to an empty alias", rule.Name, metadata.Package)
+			return nil, fmt.Errorf("applying rule %s to %q leads to an empty alias", rule.Name, metadata.Package)
 		}
 
 		if !validAlias.MatchString(newAlias) {
-			return fmt.Errorf("rule %s generated an invalid alias %q for package %q", rule.Name, newAlias, metadata.Package)
+			return nil, fmt.Errorf("rule %s generated an invalid alias %q for package %q", rule.Name, newAlias, metadata.Package)
 		}
 
-		if oldAlias == newAlias {
+		if oldName == newAlias {
 			continue
 		}
 
-		aliasRenames[oldAlias] = newAlias
-
 		// make sure whoever uses the import metadata from now on has the new aliases
-		imports[imprt].Alias = newAlias
+		planned[metadata] = &plannedAlias{rule: rule, previous: metadata.Alias}
+		metadata.Alias = newAlias
 	}
 
 	// nothing to do, the ideal situation
-	if len(aliasRenames) == 0 {
-		return nil
+	if len(planned) == 0 && unchangedNames == 0 {
+		return nil, nil
 	}
 
 	/*
 		We can thankfully rely on package names being unique, i.e.
 		it's impossible to import both core/v1 and networking/v1
 		without already having aliased one of the two. This guarantees
-		that there are no conflicts for the left side of aliasRenames.
+		that there are no conflicts for the old names of the imports.
 
 		packageNames = {
 			fmt: fmt,
@@ -129,47 +319,64 @@ func (a *Aliaser) RewriteFile(file *ast.File, filePath string, imports map[strin
 			k8s.io/api/networking/v1beta1: v1beta1,
 		}
 
-		Note that the left side is either the alias (if already set)
-		or the original package name.
+		Note that the old name is either the alias (if already set)
+		or the original package name. The new aliases however can
+		conflict with each other or with the names of other imports,
+		which is resolved using the configured strategy.
+	*/
+	conflicts, err := a.resolveConflicts(imports, planned, effectiveName)
+	if err != nil {
+		return conflicts, err
+	}
 
-		aliasRenames = {
-			v1: corev1,
-			v1beta1: networkingv1beta1,
-		}
+	// map of old package name (can be alias) to new name
+	aliasRenames := map[string]string{}
 
-		Final set of names that would be in use:
+	// map of old package name to the renamed import, to count rewrites
+	renamedImports := map[string]*importMetadata{}
 
-		finalNames = [fmt, log, v1, v1beta1]
-	*/
+	for metadata, plan := range planned {
+		oldName := plan.previous
+		if oldName == "" {
+			oldName = packageName(metadata.Package)
+		}
 
-	// ensure renames are unique
-	values := map[string]string{}
-	for from, to := range aliasRenames {
-		if oldFrom, exists := values[to]; exists {
-			return aliasError(oldFrom, from, to)
+		// a redundant alias that is equal to the package name
+		// does not require renaming any identifiers
+		if newName := effectiveName(metadata); newName != oldName {
+			aliasRenames[oldName] = newName
+			renamedImports[oldName] = metadata
 		}
+	}
 
-		values[to] = from
+	// ensure new aliases do not collide with declarations in the package
+	if err := a.checkDeclarationCollisions(fset, file, filePath, buildTags, aliasRenames, renamedImports); err != nil {
+		return conflicts, err
 	}
 
-	// ensure introducing new aliases doesn't lead to naming conflicts with
-	// existing, un-aliased imports
-	values = map[string]string{}
-	for _, metadata := range imports {
-		effectiveName := metadata.Alias
-		if effectiveName == "." {
-			continue
-		}
+	// find all identifiers that need to be renamed before touching anything,
+	// so that the file is left untouched if renaming fails
+	if !a.typeCheck {
+		typed = nil
+	} else if typed == nil {
+		var err error
 
-		if effectiveName == "" {
-			effectiveName, _ = a.deps.GetPackageName(filePath, buildTags, metadata.Package)
+		typed, err = a.loader.typeCheck(file, filePath, buildTags)
+		if err != nil {
+			return conflicts, fmt.Errorf("failed to type-check: %v", err)
 		}
+	}
 
-		if otherPackage, exists := values[effectiveName]; exists {
-			return aliasError(metadata.Package, otherPackage, effectiveName)
-		}
+	idents, err := findRenames(fset, file, typed, aliasRenames, renamedImports)
+	if err != nil {
+		return conflicts, err
+	}
 
-		values[effectiveName] = metadata.Package
+	// type-checking already took care of local declarations
+	if !a.typeCheck {
+		if err := checkLocalCollisions(fset, file, idents, aliasRenames, renamedImports); err != nil {
+			return conflicts, err
+		}
 	}
 
 	// update keys in metadata map
@@ -182,10 +389,27 @@ func (a *Aliaser) RewriteFile(file *ast.File, filePath string, imports map[strin
 		imports[k] = v
 	}
 
-	// with the rename map ready, it's now time to walk the file and replace old aliases
+	// with the identifiers known, it's now time to replace the old aliases
+	for _, ident := range idents {
+		renamedImports[ident.Name].Renames++
+		ident.Name = aliasRenames[ident.Name]
+	}
+
+	return conflicts, nil
+}
+
+// findRenames returns all identifiers that refer to one of the renamed
+// packages. Without type-checking, every selector whose left side matches
+// an old package name is returned. With type-checking, only identifiers that
+// resolve to the imported package are returned, and an error is returned if
+// the new alias would be shadowed by a local declaration.
+func findRenames(fset *token.FileSet, file *ast.File, typed *typedFile, aliasRenames map[string]string, renamedImports map[string]*importMetadata) ([]*ast.Ident, error) {
+	idents := []*ast.Ident{}
+
+	var err error
 	ast.Walk(visitFn(func(node ast.Node) {
 		sel, ok := node.(*ast.SelectorExpr)
-		if !ok {
+		if !ok || err != nil {
 			return
 		}
 
@@ -194,17 +418,60 @@ func (a *Aliaser) RewriteFile(file *ast.File, filePath string, imports map[strin
 			return
 		}
 
-		localIdentifier := ident.Name
-
-		newName, ok := aliasRenames[localIdentifier]
+		newName, ok := aliasRenames[ident.Name]
 		if !ok {
 			return
 		}
 
-		ident.Name = newName
+		if typed != nil {
+			obj, ok := typed.info.Uses[ident]
+			if !ok {
+				err = fmt.Errorf("cannot determine what %q in line %d refers to", ident.Name, fset.Position(ident.Pos()).Line)
+				return
+			}
+
+			// a local variable (or similar) shadowing the package
+			if _, ok := obj.(*types.PkgName); !ok {
+				return
+			}
+
+			if scope := typed.pkg.Scope().Innermost(ident.Pos()); scope != nil {
+				if _, shadow := scope.LookupParent(newName, ident.Pos()); shadow != nil && isLocal(shadow, typed.pkg) {
+					err = fmt.Errorf("new alias %q for %q would be shadowed in line %d by %q declared in line %d",
+						newName, renamedImports[ident.Name].Package, fset.Position(ident.Pos()).Line, shadow.Name(), fset.Position(shadow.Pos()).Line)
+					return
+				}
+			}
+		}
+
+		idents = append(idents, ident)
 	}), file)
 
-	return nil
+	return idents, err
+}
+
+// packageNameFunc returns a function that lazily determines the name of the given package.
+func (a *Aliaser) packageNameFunc(filePath string, buildTags string, pkg string) func() (string, error) {
+	return func() (string, error) {
+		return a.deps.GetPackageName(filePath, buildTags, pkg)
+	}
+}
+
+// versionAlias returns the package name of a versioned package, if it differs
+// from the last path element (i.e. "doublestar" for "github.com/bmatcuk/doublestar/v4",
+// but not "v1" for "k8s.io/api/core/v1"). An empty string is returned if no
+// alias is required.
+func (a *Aliaser) versionAlias(filePath string, buildTags string, pkg string) (string, error) {
+	name, err := a.deps.GetPackageName(filePath, buildTags, pkg)
+	if err != nil {
+		return "", fmt.Errorf("invalid state: file imports %q, but: %w", pkg, err)
+	}
+
+	if name == path.Base(pkg) {
+		return "", nil
+	}
+
+	return name, nil
 }
 
 // aliasError is just to make sure the order of both mentioned packages
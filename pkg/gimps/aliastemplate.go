@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// aliasTemplateFuncs are available in alias templates. Functions taking
+// multiple arguments expect the string to work on last, so that they can
+// be used in pipelines like `{{ index .Groups 1 | replace "-" "" }}`.
+var aliasTemplateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"segments":   func(s string) []string { return strings.Split(s, "/") },
+	"last":       lastSegments,
+	"join":       func(sep string, elems []string) string { return strings.Join(elems, sep) },
+	"sanitize":   sanitizeAlias,
+}
+
+// aliasTemplateData is the data available in alias templates.
+type aliasTemplateData struct {
+	// Path is the import path, like "k8s.io/api/core/v1".
+	Path string
+	// Groups are the expression's submatches, with the entire match at index 0.
+	Groups []string
+
+	packageName func() (string, error)
+}
+
+// PackageName returns the actual name of the imported package. It is only
+// determined if the template uses it, as this requires loading packages.
+func (d aliasTemplateData) PackageName() (string, error) {
+	return d.packageName()
+}
+
+// ParseAliasTemplate parses an alias that uses the template syntax.
+func ParseAliasTemplate(name string, alias string) (*template.Template, error) {
+	return template.New(name).Funcs(aliasTemplateFuncs).Option("missingkey=error").Parse(alias)
+}
+
+// isAliasTemplate returns true if the alias uses the template syntax
+// instead of regexp substitutions.
+func isAliasTemplate(alias string) bool {
+	return strings.Contains(alias, "{{")
+}
+
+// lastSegments returns the last n elements of the path, concatenated
+// without a separator, e.g. "corev1" for ("k8s.io/api/core/v1", 2).
+func lastSegments(n int, path string) (string, error) {
+	if n < 1 {
+		return "", errors.New("last: n must be >= 1")
+	}
+
+	parts := strings.Split(path, "/")
+	if n < len(parts) {
+		parts = parts[len(parts)-n:]
+	}
+
+	return strings.Join(parts, ""), nil
+}
+
+var invalidAliasChars = regexp.MustCompile(`[^a-z0-9_]`)
+
+// sanitizeAlias lowercases the string and removes all characters
+// that are not allowed in an alias.
+func sanitizeAlias(s string) string {
+	return invalidAliasChars.ReplaceAllString(strings.ToLower(s), "")
+}
+
+// generateAlias returns the alias for the given package, which must match
+// the rule's expression. packageName is only called if the rule's template
+// needs the actual package name.
+func (r *AliasRule) generateAlias(pkg string, packageName func() (string, error)) (string, error) {
+	if r.template == nil {
+		return r.regexp.ReplaceAllString(pkg, r.Alias), nil
+	}
+
+	data := aliasTemplateData{
+		Path:        pkg,
+		Groups:      r.regexp.FindStringSubmatch(pkg),
+		packageName: packageName,
+	}
+
+	var buf strings.Builder
+	if err := r.template.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAliasFromTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		alias    string
+		pkg      string
+		expected string
+	}{
+		{
+			name:     "regexp replacement",
+			expr:     `^k8s.io/api/([a-z0-9-]+)/(v[a-z0-9-]+)$`,
+			alias:    "$1$2",
+			pkg:      "k8s.io/api/core/v1",
+			expected: "corev1",
+		},
+		{
+			name:     "groups with dashes",
+			expr:     `^example.com/apis/([a-z0-9-]+)/(v[a-z0-9]+)$`,
+			alias:    `{{ index .Groups 1 | replace "-" "" }}{{ index .Groups 2 }}`,
+			pkg:      "example.com/apis/cluster-api/v1beta1",
+			expected: "clusterapiv1beta1",
+		},
+		{
+			name:     "last segments",
+			expr:     `^k8s.io/api/`,
+			alias:    `{{ last 2 .Path }}`,
+			pkg:      "k8s.io/api/core/v1",
+			expected: "corev1",
+		},
+		{
+			name:     "sanitize",
+			expr:     `^example.com/`,
+			alias:    `{{ last 1 .Path | sanitize }}`,
+			pkg:      "example.com/Foo-Bar.io",
+			expected: "foobario",
+		},
+		{
+			name:     "package name",
+			expr:     `^gopkg.in/`,
+			alias:    `{{ .PackageName }}{{ .Path | trimPrefix "gopkg.in/" | trimSuffix ".v3" | segments | len }}`,
+			pkg:      "gopkg.in/yaml.v3",
+			expected: "yaml1",
+		},
+		{
+			name:     "join and upper",
+			expr:     `^(.+)$`,
+			alias:    `{{ segments .Path | join "_" | lower }}`,
+			pkg:      "Foo/Bar",
+			expected: "foo_bar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := AliasRule{
+				Name:       "test",
+				Expression: tt.expr,
+				Alias:      tt.alias,
+			}
+
+			rules := []AliasRule{rule}
+			if err := compileAliasRules(rules); err != nil {
+				t.Fatalf("Failed to compile rule: %v", err)
+			}
+
+			result, err := rules[0].generateAlias(tt.pkg, func() (string, error) {
+				return guessPackageName(tt.pkg), nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to generate alias: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("generateAlias() returned %q, but wanted %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGenerateAliasFromTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		alias    string
+		expected string
+	}{
+		{
+			name:     "no segments",
+			alias:    `{{ last 0 .Path }}`,
+			expected: "last: n must be >= 1",
+		},
+		{
+			name:     "negative number of segments",
+			alias:    `{{ last -1 .Path }}`,
+			expected: "last: n must be >= 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := []AliasRule{{
+				Name:       "test",
+				Expression: `^k8s.io/api/`,
+				Alias:      tt.alias,
+			}}
+
+			if err := compileAliasRules(rules); err != nil {
+				t.Fatalf("Failed to compile rule: %v", err)
+			}
+
+			_, err := rules[0].generateAlias("k8s.io/api/core/v1", nil)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, but got %v", tt.expected, err)
+			}
+		})
+	}
+}
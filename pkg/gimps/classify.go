@@ -4,10 +4,9 @@
 package gimps
 
 import (
+	"fmt"
+	"slices"
 	"strings"
-
-	doublestar "github.com/bmatcuk/doublestar/v4"
-	"github.com/incu6us/goimports-reviser/v3/pkg/std"
 )
 
 type importSet []string
@@ -19,33 +18,100 @@ const (
 )
 
 type Classifier struct {
-	projectName string
-	sets        []Set
+	projectName  string
+	sets         []setMatcher
+	stdDetection string
+	matching     string
+	goMod        *goModFile
+	subgroupBy   map[string]string
 }
 
 type Set struct {
-	Name     string   `yaml:"name"`
+	Name string `yaml:"name"`
+	// Patterns are doublestar globs (like "k8s.io/**") or, when prefixed
+	// with "regex:", Go regular expressions. Patterns prefixed with "!"
+	// exclude matching packages from the set.
 	Patterns []string `yaml:"patterns"`
+	// GoMod adds all imports from modules of the given kind in the
+	// go.mod file to the set, one of the GoModSources.
+	GoMod string `yaml:"goMod,omitempty"`
+	// SubgroupBy separates the imports of the set into subgroups, one of
+	// the SubgroupModes. This is the only setting that can be configured
+	// for the predefined sets.
+	SubgroupBy string `yaml:"subgroupBy,omitempty"`
 }
 
-func NewClassifier(projectName string, sets []Set) *Classifier {
-	return &Classifier{
-		projectName: projectName,
-		sets:        sets,
+// isPredefinedSet returns true for the sets that gimps always provides.
+func isPredefinedSet(name string) bool {
+	return name == SetStd || name == SetProject || name == SetExternal
+}
+
+// ClassifierOptions configure a Classifier.
+type ClassifierOptions struct {
+	// ProjectName is the module path of the project.
+	ProjectName string
+	// Sets are the custom sets imports can belong to.
+	Sets []Set
+	// StdDetection is one of the StdDetectionModes, defaults to
+	// StdDetectionStatic.
+	StdDetection string
+	// Matching is one of the SetMatchingModes, defaults to SetMatchingFirst.
+	Matching string
+}
+
+// NewClassifier validates the options and returns a classifier for them.
+// The patterns of all sets are compiled once, an error is returned if any
+// of them is invalid. A classifier can be used concurrently.
+func NewClassifier(opts ClassifierOptions) (*Classifier, error) {
+	if opts.StdDetection != "" && !slices.Contains(StdDetectionModes, opts.StdDetection) {
+		return nil, fmt.Errorf("invalid std detection mode %q", opts.StdDetection)
+	}
+
+	if opts.Matching != "" && !slices.Contains(SetMatchingModes, opts.Matching) {
+		return nil, fmt.Errorf("invalid set matching mode %q", opts.Matching)
 	}
+
+	matchers := []setMatcher{}
+	subgroupBy := map[string]string{}
+
+	for _, set := range opts.Sets {
+		if set.SubgroupBy != "" {
+			if !slices.Contains(SubgroupModes, set.SubgroupBy) {
+				return nil, fmt.Errorf("set %q: invalid subgroupBy %q", set.Name, set.SubgroupBy)
+			}
+
+			subgroupBy[set.Name] = set.SubgroupBy
+		}
+
+		// predefined sets only configure their subgroups
+		if isPredefinedSet(set.Name) && len(set.Patterns) == 0 && set.GoMod == "" {
+			continue
+		}
+
+		matcher, err := compileSet(set)
+		if err != nil {
+			return nil, fmt.Errorf("set %q: %w", set.Name, err)
+		}
+
+		matchers = append(matchers, matcher)
+	}
+
+	return &Classifier{
+		projectName:  opts.ProjectName,
+		sets:         matchers,
+		stdDetection: opts.StdDetection,
+		matching:     opts.Matching,
+		subgroupBy:   subgroupBy,
+	}, nil
 }
 
 func (c *Classifier) ClassifyImport(pkg string) string {
-	if _, ok := std.StdPackages[pkg]; ok {
+	if c.IsStdImport(pkg) {
 		return SetStd
 	}
 
-	for _, set := range c.sets {
-		for _, pattern := range set.Patterns {
-			if matches, _ := doublestar.Match(pattern, pkg); matches {
-				return set.Name
-			}
-		}
+	if setName := c.matchSet(pkg); setName != "" {
+		return setName
 	}
 
 	if c.IsProjectImport(pkg) {
@@ -55,6 +121,34 @@ func (c *Classifier) ClassifyImport(pkg string) string {
 	return SetExternal
 }
 
+// matchSet returns the name of the set the package belongs to, according
+// to the configured matching mode, or an empty string if no set matches.
+func (c *Classifier) matchSet(pkg string) string {
+	var (
+		bestSet         string
+		bestSpecificity specificity
+	)
+
+	for _, set := range c.sets {
+		specificity, ok := set.match(pkg, c.goMod)
+		if !ok {
+			continue
+		}
+
+		if c.matching != SetMatchingSpecific {
+			return set.name
+		}
+
+		// on ties, the earlier set wins
+		if bestSet == "" || specificity.compare(bestSpecificity) > 0 {
+			bestSet = set.name
+			bestSpecificity = specificity
+		}
+	}
+
+	return bestSet
+}
+
 func (c *Classifier) IsProjectImport(pkg string) bool {
 	return pkg == c.projectName || strings.HasPrefix(pkg, c.projectName+"/")
 }
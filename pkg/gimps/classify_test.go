@@ -4,6 +4,11 @@
 package gimps
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -42,7 +47,10 @@ func TestIsProjectImport(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			classifier := NewClassifier(tt.projectName, nil)
+			classifier, err := NewClassifier(ClassifierOptions{ProjectName: tt.projectName})
+			if err != nil {
+				t.Fatalf("Failed to create classifier: %v", err)
+			}
 
 			result := classifier.IsProjectImport(tt.importPath)
 			if result != tt.expected {
@@ -51,3 +59,342 @@ func TestIsProjectImport(t *testing.T) {
 		})
 	}
 }
+
+// fakeToolchain replaces the go command with one that lists the given
+// standard library packages, or fails if stdPackages is nil, and caches
+// the list in a temporary directory.
+func fakeToolchain(t *testing.T, stdPackages []string) string {
+	t.Helper()
+
+	oldCommand, oldCacheDir := goCommand, stdCacheDir
+	t.Cleanup(func() {
+		goCommand, stdCacheDir = oldCommand, oldCacheDir
+		toolchainStdOnce, toolchainStdPackages = sync.Once{}, nil
+	})
+
+	cacheDir := t.TempDir()
+	stdCacheDir = func() (string, error) { return cacheDir, nil }
+	toolchainStdOnce, toolchainStdPackages = sync.Once{}, nil
+
+	goCommand = func(args ...string) ([]byte, error) {
+		if stdPackages == nil {
+			return nil, errors.New("go: command not found")
+		}
+
+		switch strings.Join(args, " ") {
+		case "env GOROOT GOVERSION":
+			return []byte("/usr/local/go\ngo1.42.0\n"), nil
+		case "list std":
+			return []byte(strings.Join(stdPackages, "\n") + "\n"), nil
+		default:
+			return nil, errors.New("unexpected command")
+		}
+	}
+
+	return cacheDir
+}
+
+func TestIsStdImport(t *testing.T) {
+	tests := []struct {
+		name         string
+		stdDetection string
+		noToolchain  bool
+		importPath   string
+		expected     bool
+	}{
+		{
+			name:         "static list",
+			stdDetection: StdDetectionStatic,
+			importPath:   "net/http",
+			expected:     true,
+		},
+		{
+			name:         "static list does not know all packages",
+			stdDetection: StdDetectionStatic,
+			importPath:   "unique",
+			expected:     false,
+		},
+		{
+			name:         "toolchain",
+			stdDetection: StdDetectionToolchain,
+			importPath:   "unique",
+			expected:     true,
+		},
+		{
+			name:         "toolchain is authoritative",
+			stdDetection: StdDetectionToolchain,
+			importPath:   "notstd",
+			expected:     false,
+		},
+		{
+			name:         "toolchain with external package",
+			stdDetection: StdDetectionToolchain,
+			importPath:   "github.com/foo/bar",
+			expected:     false,
+		},
+		{
+			name:         "unavailable toolchain falls back to static list",
+			stdDetection: StdDetectionToolchain,
+			noToolchain:  true,
+			importPath:   "net/http",
+			expected:     true,
+		},
+		{
+			name:         "unavailable toolchain falls back to heuristic",
+			stdDetection: StdDetectionToolchain,
+			noToolchain:  true,
+			importPath:   "unique",
+			expected:     true,
+		},
+		{
+			name:         "unavailable toolchain with project package",
+			stdDetection: StdDetectionToolchain,
+			noToolchain:  true,
+			importPath:   "myproject/pkg/util",
+			expected:     false,
+		},
+		{
+			name:         "heuristic",
+			stdDetection: StdDetectionHeuristic,
+			importPath:   "crypto/mlkem",
+			expected:     true,
+		},
+		{
+			name:         "heuristic with external package",
+			stdDetection: StdDetectionHeuristic,
+			importPath:   "gopkg.in/yaml.v3",
+			expected:     false,
+		},
+		{
+			name:         "heuristic with dotless project package",
+			stdDetection: StdDetectionHeuristic,
+			importPath:   "myproject/pkg/util",
+			expected:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdPackages := []string{"fmt", "net/http", "unique"}
+			if tt.noToolchain {
+				stdPackages = nil
+			}
+
+			fakeToolchain(t, stdPackages)
+
+			classifier, err := NewClassifier(ClassifierOptions{
+				ProjectName:  "myproject",
+				StdDetection: tt.stdDetection,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create classifier: %v", err)
+			}
+
+			result := classifier.IsStdImport(tt.importPath)
+			if result != tt.expected {
+				t.Errorf("IsStdImport() returned %v, but wanted %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestToolchainStdPackagesCache(t *testing.T) {
+	cacheDir := fakeToolchain(t, []string{"fmt", "unique"})
+
+	if _, err := loadToolchainStdPackages(); err != nil {
+		t.Fatalf("Failed to load std packages: %v", err)
+	}
+
+	cacheFiles, err := filepath.Glob(filepath.Join(cacheDir, "gimps", "std-*.txt"))
+	if err != nil || len(cacheFiles) != 1 {
+		t.Fatalf("Expected exactly one cache file, got %v (%v).", cacheFiles, err)
+	}
+
+	// the cached list is used as long as GOROOT and the Go version are unchanged
+	if err := os.WriteFile(cacheFiles[0], []byte("cached\n"), 0644); err != nil {
+		t.Fatalf("Failed to update cache file: %v", err)
+	}
+
+	packages, err := loadToolchainStdPackages()
+	if err != nil {
+		t.Fatalf("Failed to load std packages: %v", err)
+	}
+
+	if _, ok := packages["cached"]; !ok || len(packages) != 1 {
+		t.Errorf("Expected cached package list, got %v.", packages)
+	}
+}
+
+func TestClassifyImportWithSets(t *testing.T) {
+	sets := []Set{
+		{
+			Name:     "kubernetes",
+			Patterns: []string{"k8s.io/**", "!k8s.io/utils/**", "regex:^sigs\\.k8s\\.io/(controller-runtime|yaml)(/|$)"},
+		},
+		{
+			Name:     "utils",
+			Patterns: []string{"k8s.io/utils/**"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		importPath string
+		expected   string
+	}{
+		{
+			name:       "glob",
+			importPath: "k8s.io/api/core/v1",
+			expected:   "kubernetes",
+		},
+		{
+			name:       "excluded by negated pattern falls through to next set",
+			importPath: "k8s.io/utils/pointer",
+			expected:   "utils",
+		},
+		{
+			name:       "regular expression",
+			importPath: "sigs.k8s.io/controller-runtime/pkg/client",
+			expected:   "kubernetes",
+		},
+		{
+			name:       "regular expression does not match",
+			importPath: "sigs.k8s.io/controller-tools",
+			expected:   SetExternal,
+		},
+		{
+			name:       "std",
+			importPath: "fmt",
+			expected:   SetStd,
+		},
+	}
+
+	classifier, err := NewClassifier(ClassifierOptions{
+		ProjectName: "github.com/foo/bar",
+		Sets:        sets,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := classifier.ClassifyImport(tt.importPath)
+			if result != tt.expected {
+				t.Errorf("ClassifyImport() returned %q, but wanted %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewClassifierInvalidPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+	}{
+		{
+			name:     "invalid glob",
+			patterns: []string{"k8s.io/[**"},
+		},
+		{
+			name:     "invalid regular expression",
+			patterns: []string{"regex:k8s.io/(api"},
+		},
+		{
+			name:     "only exclusions",
+			patterns: []string{"!k8s.io/**"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClassifier(ClassifierOptions{
+				ProjectName: "github.com/foo/bar",
+				Sets:        []Set{{Name: "test", Patterns: tt.patterns}},
+			})
+			if err == nil {
+				t.Fatal("Expected an error, but got none")
+			}
+		})
+	}
+}
+
+func TestClassifyImportWithSpecificMatching(t *testing.T) {
+	sets := []Set{
+		{
+			Name:     "kubermatic",
+			Patterns: []string{"github.com/kubermatic/**"},
+		},
+		{
+			Name:     "machine-controller",
+			Patterns: []string{"github.com/kubermatic/machine-controller/**", "!github.com/kubermatic/machine-controller/sdk/**"},
+		},
+		{
+			Name:     "operator",
+			Patterns: []string{"regex:^github\\.com/kubermatic/kubermatic/pkg/(apis|crd)/", "regex:operator"},
+		},
+		{
+			Name:     "exact",
+			Patterns: []string{"github.com/kubermatic/machine-controller", "github.com/kubermatic/*"},
+		},
+		{
+			Name:     "tie",
+			Patterns: []string{"github.com/kubermatic/**"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		importPath string
+		expected   string
+	}{
+		{
+			name:       "ties go to the earlier set",
+			importPath: "github.com/kubermatic/kubeone",
+			expected:   "kubermatic",
+		},
+		{
+			name:       "longer glob wins",
+			importPath: "github.com/kubermatic/machine-controller/pkg/apis",
+			expected:   "machine-controller",
+		},
+		{
+			name:       "excluded from more specific set",
+			importPath: "github.com/kubermatic/machine-controller/sdk/providerconfig",
+			expected:   "kubermatic",
+		},
+		{
+			name:       "anchored regular expression",
+			importPath: "github.com/kubermatic/kubermatic/pkg/apis/v1",
+			expected:   "operator",
+		},
+		{
+			name:       "unanchored regular expression is not specific",
+			importPath: "github.com/kubermatic/kubermatic/pkg/operator",
+			expected:   "kubermatic",
+		},
+		{
+			name:       "exact match wins",
+			importPath: "github.com/kubermatic/machine-controller",
+			expected:   "exact",
+		},
+	}
+
+	classifier, err := NewClassifier(ClassifierOptions{
+		ProjectName: "github.com/foo/bar",
+		Sets:        sets,
+		Matching:    SetMatchingSpecific,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := classifier.ClassifyImport(tt.importPath)
+			if result != tt.expected {
+				t.Errorf("ClassifyImport() returned %q, but wanted %q", result, tt.expected)
+			}
+		})
+	}
+}
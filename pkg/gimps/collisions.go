@@ -0,0 +1,268 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// declaration is a named declaration in a Go file, used to report
+// collisions with new aliases.
+type declaration struct {
+	kind     string
+	name     string
+	position token.Position
+}
+
+func (d declaration) String() string {
+	return fmt.Sprintf("%s %q declared in %s:%d", d.kind, d.name, d.position.Filename, d.position.Line)
+}
+
+// before returns true if the declaration comes before the other one, in
+// the order of filenames and lines.
+func (d declaration) before(other declaration) bool {
+	if d.position.Filename != other.position.Filename {
+		return d.position.Filename < other.position.Filename
+	}
+
+	return d.position.Line < other.position.Line
+}
+
+// fileDeclarations are the top-level declarations of a single file.
+type fileDeclarations struct {
+	packageName  string
+	declarations map[string]declaration
+}
+
+type declarationsCacheEntry struct {
+	once  sync.Once
+	files map[string]fileDeclarations
+	err   error
+}
+
+// getDeclarations parses all Go files (including tests) in the given
+// directory that match the given build tags and returns their top-level
+// declarations, keyed by absolute filename.
+func (c *DependencyCache) getDeclarations(directory string, buildTags string) (map[string]fileDeclarations, error) {
+	key := dependencyCacheKey{
+		directory: directory,
+		buildTags: buildTags,
+	}
+
+	c.lock.Lock()
+	entry, ok := c.declarations[key]
+	if !ok {
+		entry = &declarationsCacheEntry{}
+		c.declarations[key] = entry
+	}
+	c.lock.Unlock()
+
+	entry.once.Do(func() {
+		entry.files, entry.err = loadDeclarations(directory, buildTags)
+		if entry.err != nil {
+			entry.err = fmt.Errorf("failed to load package declarations: %v", entry.err)
+		}
+	})
+
+	return entry.files, entry.err
+}
+
+func loadDeclarations(directory string, buildTags string) (map[string]fileDeclarations, error) {
+	ctx := build.Default
+	if buildTags != "" {
+		ctx.BuildTags = strings.Split(buildTags, ",")
+	}
+
+	pkg, err := ctx.ImportDir(directory, 0)
+	if err != nil {
+		var noGoErr *build.NoGoError
+		if errors.As(err, &noGoErr) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	filenames := []string{}
+	filenames = append(filenames, pkg.GoFiles...)
+	filenames = append(filenames, pkg.CgoFiles...)
+	filenames = append(filenames, pkg.TestGoFiles...)
+	filenames = append(filenames, pkg.XTestGoFiles...)
+
+	fset := token.NewFileSet()
+	files := map[string]fileDeclarations{}
+
+	for _, filename := range filenames {
+		fullPath := filepath.Join(pkg.Dir, filename)
+
+		file, err := parser.ParseFile(fset, fullPath, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+
+		files[fullPath] = fileDeclarations{
+			packageName:  file.Name.Name,
+			declarations: topLevelDeclarations(fset, file, filename),
+		}
+	}
+
+	return files, nil
+}
+
+// topLevelDeclarations returns all package-level identifiers declared in
+// the file. Methods are not included, as they do not occupy a name in the
+// package scope.
+func topLevelDeclarations(fset *token.FileSet, file *ast.File, filename string) map[string]declaration {
+	result := map[string]declaration{}
+
+	add := func(kind string, ident *ast.Ident) {
+		if ident.Name == "_" {
+			return
+		}
+
+		result[ident.Name] = declaration{
+			kind:     kind,
+			name:     ident.Name,
+			position: token.Position{Filename: filename, Line: fset.Position(ident.Pos()).Line},
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				add("func", d.Name)
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						add(d.Tok.String(), name)
+					}
+
+				case *ast.TypeSpec:
+					add("type", s.Name)
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// checkDeclarationCollisions ensures that none of the new aliases collide
+// with a top-level declaration in the given file or, if the aliaser loads
+// packages from disk, in any other file of the same package.
+func (a *Aliaser) checkDeclarationCollisions(fset *token.FileSet, file *ast.File, filePath string, buildTags string, aliasRenames map[string]string, renamedImports map[string]*importMetadata) error {
+	filename := filepath.Base(filePath)
+	declarations := []map[string]declaration{
+		topLevelDeclarations(fset, file, filename),
+	}
+
+	if a.loader != nil {
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to determine absolute path: %v", err)
+		}
+
+		files, err := a.loader.getDeclarations(filepath.Dir(absPath), buildTags)
+		if err != nil {
+			return err
+		}
+
+		for otherPath, other := range files {
+			// the file on disk might differ from the version that is being formatted
+			if otherPath != absPath && other.packageName == file.Name.Name {
+				declarations = append(declarations, other.declarations)
+			}
+		}
+	}
+
+	// iterate over the imports in a stable order, so that errors are stable
+	oldAliases := []string{}
+	for oldAlias := range aliasRenames {
+		oldAliases = append(oldAliases, oldAlias)
+	}
+	sort.Strings(oldAliases)
+
+	for _, oldAlias := range oldAliases {
+		newAlias := aliasRenames[oldAlias]
+
+		var collision *declaration
+		for _, decls := range declarations {
+			if decl, exists := decls[newAlias]; exists && (collision == nil || decl.before(*collision)) {
+				collision = &decl
+			}
+		}
+
+		if collision != nil {
+			return fmt.Errorf("new alias %q for %q in %s collides with %s", newAlias, renamedImports[oldAlias].Package, filename, collision)
+		}
+	}
+
+	return nil
+}
+
+// checkLocalCollisions ensures that none of the identifiers that are about
+// to be renamed would refer to a local declaration of the same name
+// afterwards. Without type information, this is done conservatively: any
+// declaration of the new alias inside a function that also uses the
+// renamed package is considered a collision.
+func checkLocalCollisions(fset *token.FileSet, file *ast.File, idents []*ast.Ident, aliasRenames map[string]string, renamedImports map[string]*importMetadata) error {
+	renamed := map[*ast.Ident]struct{}{}
+	for _, ident := range idents {
+		renamed[ident] = struct{}{}
+	}
+
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+
+		// all local declarations in this function, by name
+		locals := map[string]*ast.Ident{}
+		// all package references in this function
+		uses := []*ast.Ident{}
+
+		ast.Inspect(funcDecl, func(node ast.Node) bool {
+			ident, ok := node.(*ast.Ident)
+			if !ok {
+				return true
+			}
+
+			if _, ok := renamed[ident]; ok {
+				uses = append(uses, ident)
+			} else if ident.Obj != nil && ident.Obj.Pos() == ident.Pos() && ident != funcDecl.Name {
+				if _, exists := locals[ident.Name]; !exists {
+					locals[ident.Name] = ident
+				}
+			}
+
+			return true
+		})
+
+		for _, use := range uses {
+			newAlias := aliasRenames[use.Name]
+
+			if local, exists := locals[newAlias]; exists {
+				return fmt.Errorf("new alias %q for %q would be shadowed in line %d by %q declared in line %d",
+					newAlias, renamedImports[use.Name].Package, fset.Position(use.Pos()).Line, local.Name, fset.Position(local.Pos()).Line)
+			}
+		}
+	}
+
+	return nil
+}
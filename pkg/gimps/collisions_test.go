@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"go/token"
+	"testing"
+)
+
+func TestDeclarationBefore(t *testing.T) {
+	decl := func(filename string, line int) declaration {
+		return declaration{
+			kind:     "var",
+			name:     "nethttp",
+			position: token.Position{Filename: filename, Line: line},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		a        declaration
+		b        declaration
+		expected bool
+	}{
+		{
+			name:     "lines are compared as numbers",
+			a:        decl("types.go", 9),
+			b:        decl("types.go", 10),
+			expected: true,
+		},
+		{
+			name:     "later line",
+			a:        decl("types.go", 10),
+			b:        decl("types.go", 9),
+			expected: false,
+		},
+		{
+			name:     "filename takes precedence",
+			a:        decl("a.go", 100),
+			b:        decl("b.go", 1),
+			expected: true,
+		},
+		{
+			name:     "same position",
+			a:        decl("types.go", 9),
+			b:        decl("types.go", 9),
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.a.before(tt.b); result != tt.expected {
+				t.Errorf("before() returned %v, but wanted %v", result, tt.expected)
+			}
+		})
+	}
+
+	if s := decl("types.go", 10).String(); s != `var "nethttp" declared in types.go:10` {
+		t.Errorf("Unexpected string representation %q.", s)
+	}
+}
@@ -8,6 +8,55 @@ type Config struct {
 	ImportOrder []string    `yaml:"importOrder"`
 	Sets        []Set       `yaml:"sets"`
 	AliasRules  []AliasRule `yaml:"aliasRules"`
+
+	// FallbackSet is the name of a set from the ImportOrder. Imports that
+	// belong to a set which is not listed in the ImportOrder are placed
+	// into the fallback set. If no fallback is configured, such imports
+	// make formatting the file fail.
+	FallbackSet string `yaml:"fallbackSet"`
+
+	// Matching controls which set an import belongs to if the patterns of
+	// multiple sets match, one of the SetMatchingModes. Defaults to
+	// SetMatchingFirst.
+	Matching string `yaml:"matching"`
+
+	// StdDetection controls how imports are recognized as belonging to the
+	// standard library, one of the StdDetectionModes. Defaults to
+	// StdDetectionStatic.
+	StdDetection string `yaml:"stdDetection"`
+
+	// RemoveUnusedImports enables removing imports that are never
+	// referenced in a file. Blank and dot imports are always kept.
+	RemoveUnusedImports bool `yaml:"removeUnusedImports"`
+
+	// SetVersionAlias enables adding an explicit alias to imports with a
+	// major version suffix, if the package name differs from the last
+	// path element, e.g. `doublestar "github.com/bmatcuk/doublestar/v4"`.
+	SetVersionAlias bool `yaml:"setVersionAlias"`
+
+	// TypeCheck enables type-checking the package of each file before
+	// applying alias rules, so that only identifiers that actually refer
+	// to an imported package are renamed. This is slower, but correctly
+	// handles local variables that shadow package names.
+	TypeCheck bool `yaml:"typeCheck"`
+
+	// ExpandDotImports enables turning dot imports into regular imports,
+	// qualifying all identifiers that refer to the imported package. Alias
+	// rules are applied afterwards. This requires type-checking the package.
+	ExpandDotImports bool `yaml:"expandDotImports"`
+
+	// AliasConflicts is the strategy to use when two or more imports would
+	// use the same alias, one of the AliasConflictStrategies. Defaults to
+	// AliasConflictsFail.
+	AliasConflicts string `yaml:"aliasConflicts"`
+
+	// ForbiddenAliases are lint rules for aliases that must not be used.
+	ForbiddenAliases []ForbiddenAlias `yaml:"forbiddenAliases"`
+
+	// RequiredAliases are lint rules for packages that must always be
+	// imported using a specific alias (or, for unalias rules, without one).
+	// Unlike AliasRules, they are never applied automatically.
+	RequiredAliases []AliasRule `yaml:"requiredAliases"`
 }
 
 func setDefaults(c *Config) {
@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// AliasConflictsFail aborts formatting a file if two or more imports would
+	// use the same alias. This is the default.
+	AliasConflictsFail = "fail"
+
+	// AliasConflictsSkipFile leaves files with conflicting aliases unchanged.
+	AliasConflictsSkipFile = "skip-file"
+
+	// AliasConflictsSkipRule does not apply alias rules to conflicting imports.
+	AliasConflictsSkipRule = "skip-rule"
+
+	// AliasConflictsDisambiguate appends further path elements to the aliases
+	// generated by alias rules until they are unique.
+	AliasConflictsDisambiguate = "disambiguate"
+)
+
+// AliasConflictStrategies are all valid values for Config.AliasConflicts.
+var AliasConflictStrategies = []string{
+	AliasConflictsFail,
+	AliasConflictsSkipFile,
+	AliasConflictsSkipRule,
+	AliasConflictsDisambiguate,
+}
+
+// errSkipFile is returned by RewriteFile if the file must be left unchanged.
+var errSkipFile = errors.New("file skipped because of conflicting aliases")
+
+// AliasConflict describes two or more imports that would have used the same
+// alias after applying the alias rules, and how this has been resolved.
+type AliasConflict struct {
+	// Alias is the name the imports would have shared.
+	Alias string
+	// Packages are the conflicting import paths, sorted alphabetically.
+	Packages []string
+	// Strategy is the resolution that has been applied, one of the
+	// AliasConflictStrategies (except for AliasConflictsFail).
+	Strategy string
+}
+
+func (c AliasConflict) String() string {
+	var resolution string
+
+	switch c.Strategy {
+	case AliasConflictsSkipFile:
+		resolution = "left the file unchanged"
+	case AliasConflictsSkipRule:
+		resolution = "kept the original aliases"
+	case AliasConflictsDisambiguate:
+		resolution = "disambiguated the new aliases"
+	}
+
+	return fmt.Sprintf("packages %s would all be aliased to %q, %s", quoteAll(c.Packages), c.Alias, resolution)
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// plannedAlias is an alias that has been changed by an alias rule.
+type plannedAlias struct {
+	rule *AliasRule
+	// previous is the alias before applying the rule.
+	previous string
+}
+
+// resolveConflicts ensures that no two imports share the same name. The
+// planned aliases are updated according to the configured strategy and the
+// resolved conflicts are returned. If a conflict cannot be resolved,
+// an error is returned.
+func (a *Aliaser) resolveConflicts(imports map[string]*importMetadata, planned map[*importMetadata]*plannedAlias, effectiveName func(*importMetadata) string) ([]AliasConflict, error) {
+	conflicts := []AliasConflict{}
+
+	for {
+		alias, conflicting := findConflict(imports, effectiveName)
+		if conflicting == nil {
+			return conflicts, nil
+		}
+
+		packages := []string{}
+		for _, metadata := range conflicting {
+			packages = append(packages, metadata.Package)
+		}
+
+		conflict := AliasConflict{
+			Alias:    alias,
+			Packages: packages,
+			Strategy: a.aliasConflicts,
+		}
+
+		resolved := false
+
+		switch a.aliasConflicts {
+		case AliasConflictsSkipFile:
+			return append(conflicts, conflict), errSkipFile
+
+		case AliasConflictsSkipRule:
+			for _, metadata := range conflicting {
+				if plan, ok := planned[metadata]; ok {
+					metadata.Alias = plan.previous
+					delete(planned, metadata)
+					resolved = true
+				}
+			}
+
+		case AliasConflictsDisambiguate:
+			for _, metadata := range conflicting {
+				if plan, ok := planned[metadata]; ok && plan.rule.Type != AliasRuleTypeUnalias {
+					if disambiguated := disambiguateAlias(metadata.Package, metadata.Alias); disambiguated != "" {
+						metadata.Alias = disambiguated
+						resolved = true
+					}
+				}
+			}
+		}
+
+		if !resolved {
+			return nil, aliasError(packages[0], packages[1], alias)
+		}
+
+		conflicts = append(conflicts, conflict)
+	}
+}
+
+// findConflict returns the alphabetically first name that is used by more
+// than one import, together with those imports (sorted by package).
+func findConflict(imports map[string]*importMetadata, effectiveName func(*importMetadata) string) (string, []*importMetadata) {
+	byName := map[string][]*importMetadata{}
+	for _, metadata := range imports {
+		// blank and dot imports do not occupy a name
+		if metadata.Alias == "_" || metadata.Alias == "." {
+			continue
+		}
+
+		name := effectiveName(metadata)
+		byName[name] = append(byName[name], metadata)
+	}
+
+	names := []string{}
+	for name, users := range byName {
+		if len(users) > 1 {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "", nil
+	}
+
+	sort.Strings(names)
+	conflicting := byName[names[0]]
+
+	sort.Slice(conflicting, func(i, j int) bool {
+		return conflicting[i].Package < conflicting[j].Package
+	})
+
+	return names[0], conflicting
+}
+
+// disambiguateAlias appends the last path element of the package that is
+// not yet part of the alias, e.g. "k8s.io/api/core/v1" with "corev1" yields
+// "corev1api". An empty string is returned if no such element exists.
+func disambiguateAlias(pkg string, alias string) string {
+	segments := strings.Split(pkg, "/")
+
+	for i := len(segments) - 1; i >= 0; i-- {
+		segment := sanitizeAlias(segments[i])
+		if segment == "" || strings.Contains(alias, segment) {
+			continue
+		}
+
+		if candidate := alias + segment; validAlias.MatchString(candidate) {
+			return candidate
+		}
+	}
+
+	return ""
+}
@@ -5,39 +5,153 @@ package gimps
 
 import (
 	"fmt"
+	"go/ast"
+	"go/build/constraint"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/incu6us/goimports-reviser/v3/pkg/astutil"
 )
 
+// PackageNameResolver determines the name of an imported package, which
+// can differ from the last element of its import path (for example
+// "github.com/bmatcuk/doublestar/v4" is named "doublestar").
+type PackageNameResolver interface {
+	// GetPackageName returns the name of the package with the given import
+	// path, as imported by the given file, which uses the given build tags.
+	GetPackageName(filePath string, buildTags string, packagePath string) (string, error)
+}
+
 // DependencyCache holds a list of package names per
-// build tags (i.e. the key is the build tags string,
-// like "windows prod").
-type DependencyCache map[string]astutil.PackageImports
+// package directory and build tags (like "windows prod").
+// It is safe for concurrent use; packages are only ever
+// loaded once, even if multiple goroutines request them
+// at the same time.
+type DependencyCache struct {
+	lock         sync.Mutex
+	entries      map[dependencyCacheKey]*dependencyCacheEntry
+	packages     map[dependencyCacheKey]*packagesCacheEntry
+	packageFiles map[dependencyCacheKey]*packageFilesCacheEntry
+	declarations map[dependencyCacheKey]*declarationsCacheEntry
+	goMods       map[string]*goModCacheEntry
+}
+
+type dependencyCacheKey struct {
+	directory string
+	buildTags string
+}
 
-func NewDependencyCache() DependencyCache {
-	return make(DependencyCache)
+type dependencyCacheEntry struct {
+	once     sync.Once
+	packages astutil.PackageImports
+	err      error
 }
 
-func (c DependencyCache) GetPackageName(filePath string, buildTags string, packagePath string) (string, error) {
-	if _, ok := c[buildTags]; !ok {
-		c[buildTags] = astutil.PackageImports{}
+var _ PackageNameResolver = &DependencyCache{}
+
+func NewDependencyCache() *DependencyCache {
+	return &DependencyCache{
+		entries:      map[dependencyCacheKey]*dependencyCacheEntry{},
+		packages:     map[dependencyCacheKey]*packagesCacheEntry{},
+		packageFiles: map[dependencyCacheKey]*packageFilesCacheEntry{},
+		declarations: map[dependencyCacheKey]*declarationsCacheEntry{},
+		goMods:       map[string]*goModCacheEntry{},
 	}
+}
 
-	packageName, ok := c[buildTags][packagePath]
-	if !ok {
-		packageNames, err := astutil.LoadPackageDependencies(filepath.Dir(filePath), buildTags)
-		if err != nil {
-			return "", fmt.Errorf("failed to load package dependencies: %v", err)
-		}
+func (c *DependencyCache) GetPackageName(filePath string, buildTags string, packagePath string) (string, error) {
+	directory := filepath.Dir(filePath)
 
-		c[buildTags] = packageNames
-		packageName, ok = c[buildTags][packagePath]
+	packageNames, err := c.getDependencies(directory, buildTags)
+	if err != nil {
+		return "", err
 	}
 
+	packageName, ok := packageNames[packagePath]
 	if !ok {
-		return "", fmt.Errorf("package %q is not a dependency of %q", packagePath, filepath.Dir(filePath))
+		return "", fmt.Errorf("package %q is not a dependency of %q", packagePath, directory)
 	}
 
 	return packageName, nil
 }
+
+func (c *DependencyCache) getDependencies(directory string, buildTags string) (astutil.PackageImports, error) {
+	key := dependencyCacheKey{
+		directory: directory,
+		buildTags: buildTags,
+	}
+
+	c.lock.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &dependencyCacheEntry{}
+		c.entries[key] = entry
+	}
+	c.lock.Unlock()
+
+	// loading happens outside of the lock, so that different
+	// packages can be loaded in parallel
+	entry.once.Do(func() {
+		entry.packages, entry.err = astutil.LoadPackageDependencies(directory, buildTags)
+		if entry.err != nil {
+			entry.err = fmt.Errorf("failed to load package dependencies: %v", entry.err)
+		}
+	})
+
+	return entry.packages, entry.err
+}
+
+// parseBuildTags returns the tags mentioned in the file's build constraints
+// as a comma separated list, suitable for `go list -tags`. Negated tags are
+// ignored, so that loading the package with the resulting tags includes
+// the given file.
+func parseBuildTags(file *ast.File) string {
+	tags := []string{}
+
+	for _, group := range file.Comments {
+		// build constraints must appear before the package clause
+		if group.Pos() > file.Package {
+			break
+		}
+
+		for _, comment := range group.List {
+			if !constraint.IsGoBuild(comment.Text) && !constraint.IsPlusBuild(comment.Text) {
+				continue
+			}
+
+			expr, err := constraint.Parse(comment.Text)
+			if err != nil {
+				continue
+			}
+
+			tags = append(tags, positiveTags(expr, false)...)
+		}
+	}
+
+	// files often contain both "//go:build" and "// +build" lines
+	slices.Sort(tags)
+
+	return strings.Join(slices.Compact(tags), ",")
+}
+
+func positiveTags(expr constraint.Expr, negated bool) []string {
+	switch e := expr.(type) {
+	case *constraint.TagExpr:
+		if !negated {
+			return []string{e.Tag}
+		}
+
+	case *constraint.NotExpr:
+		return positiveTags(e.X, !negated)
+
+	case *constraint.AndExpr:
+		return append(positiveTags(e.X, negated), positiveTags(e.Y, negated)...)
+
+	case *constraint.OrExpr:
+		return append(positiveTags(e.X, negated), positiveTags(e.Y, negated)...)
+	}
+
+	return nil
+}
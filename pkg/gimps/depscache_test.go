@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestParseBuildTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "no constraints",
+			code:     "package main",
+			expected: "",
+		},
+		{
+			name:     "single tag",
+			code:     "//go:build integration\n\npackage main",
+			expected: "integration",
+		},
+		{
+			name:     "negated tags are ignored",
+			code:     "//go:build linux && !windows\n\npackage main",
+			expected: "linux",
+		},
+		{
+			name:     "old and new style constraints",
+			code:     "//go:build linux || (darwin && cgo)\n// +build linux darwin,cgo\n\npackage main",
+			expected: "cgo,darwin,linux",
+		},
+		{
+			name:     "constraints after the package clause are ignored",
+			code:     "package main\n\n//go:build linux\n",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "", tt.code, parser.ParseComments)
+			if err != nil {
+				t.Fatalf("Failed to parse code: %v", err)
+			}
+
+			result := parseBuildTags(file)
+			if result != tt.expected {
+				t.Errorf("parseBuildTags() returned %q, but wanted %q", result, tt.expected)
+			}
+		})
+	}
+}
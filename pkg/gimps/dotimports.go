@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+func hasDotImports(imports map[string]*importMetadata) bool {
+	for _, metadata := range imports {
+		if metadata.Alias == "." {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expandDotImports turns all dot imports into regular imports and qualifies
+// every identifier that refers to a package-level object of a dot-imported
+// package. The new identifiers are registered in the type information as
+// references to the package, so that alias rules can be applied afterwards.
+// It returns the number of expanded imports.
+func expandDotImports(fset *token.FileSet, file *ast.File, typed *typedFile, imports map[string]*importMetadata) (int, error) {
+	dotImports := map[string]*importMetadata{}
+	for _, metadata := range imports {
+		if metadata.Alias == "." {
+			dotImports[metadata.Package] = metadata
+		}
+	}
+
+	if len(dotImports) == 0 {
+		return 0, nil
+	}
+
+	// selectors are never package-level objects of a dot-imported package
+	selectors := map[*ast.Ident]struct{}{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if sel, ok := node.(*ast.SelectorExpr); ok {
+			selectors[sel.Sel] = struct{}{}
+		}
+
+		return true
+	})
+
+	// find all identifiers to qualify, before modifying the file
+	qualify := map[*ast.Ident]*types.Package{}
+	for ident, obj := range typed.info.Uses {
+		if _, ok := selectors[ident]; ok {
+			continue
+		}
+
+		pkg := obj.Pkg()
+		if pkg == nil || obj.Parent() != pkg.Scope() {
+			continue
+		}
+
+		if _, ok := dotImports[pkg.Path()]; !ok {
+			continue
+		}
+
+		if scope := typed.pkg.Scope().Innermost(ident.Pos()); scope != nil {
+			if _, shadow := scope.LookupParent(pkg.Name(), ident.Pos()); shadow != nil {
+				if _, isImport := shadow.(*types.PkgName); !isImport {
+					return 0, fmt.Errorf("cannot expand dot-import of %q, %q in line %d would refer to %q declared in line %d",
+						pkg.Path(), pkg.Name(), fset.Position(ident.Pos()).Line, shadow.Name(), fset.Position(shadow.Pos()).Line)
+				}
+			}
+		}
+
+		qualify[ident] = pkg
+	}
+
+	pkgNames := map[string]*types.PkgName{}
+
+	astutil.Apply(file, nil, func(c *astutil.Cursor) bool {
+		ident, ok := c.Node().(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		pkg, ok := qualify[ident]
+		if !ok {
+			return true
+		}
+
+		pkgName, ok := pkgNames[pkg.Path()]
+		if !ok {
+			pkgName = types.NewPkgName(token.NoPos, typed.pkg, pkg.Name(), pkg)
+			pkgNames[pkg.Path()] = pkgName
+		}
+
+		qualifier := &ast.Ident{
+			NamePos: ident.Pos(),
+			Name:    pkg.Name(),
+		}
+		typed.info.Uses[qualifier] = pkgName
+
+		c.Replace(&ast.SelectorExpr{
+			X:   qualifier,
+			Sel: ident,
+		})
+
+		dotImports[pkg.Path()].Renames++
+
+		return true
+	})
+
+	for _, metadata := range dotImports {
+		metadata.Alias = ""
+	}
+
+	return len(dotImports), nil
+}
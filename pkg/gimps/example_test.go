@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps_test
+
+import (
+	"fmt"
+	"log"
+
+	"go.xrstf.de/gimps/pkg/gimps"
+)
+
+func ExampleFormat() {
+	source := []byte(`package main
+
+import (
+	"k8s.io/api/core/v1"
+	"fmt"
+	"example.com/project/pkg/util"
+)
+
+func main() {
+	fmt.Println(v1.Pod{}, util.Version)
+}
+`)
+
+	config := &gimps.Config{
+		ProjectName: "example.com/project",
+		AliasRules: []gimps.AliasRule{{
+			Name:       "k8s-api",
+			Expression: `^k8s.io/api/([a-z0-9-]+)/(v[a-z0-9-]+)$`,
+			Alias:      "$1$2",
+		}},
+	}
+
+	// resolve package names in memory instead of loading them from disk
+	aliaser, err := gimps.NewAliaserWithResolver(config, gimps.PackageNames{
+		"k8s.io/api/core/v1":           "v1",
+		"example.com/project/pkg/util": "util",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the filename is only a hint and does not need to exist
+	result, err := gimps.Format(config, "cmd/main.go", source, aliaser)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Print(string(result.Output))
+
+	for _, imprt := range result.Imports {
+		fmt.Printf("%s: %q => %q (%s)\n", imprt.Package, imprt.OldAlias, imprt.NewAlias, imprt.Set)
+	}
+
+	// Output:
+	// package main
+	//
+	// import (
+	// 	"fmt"
+	//
+	// 	"example.com/project/pkg/util"
+	//
+	// 	corev1 "k8s.io/api/core/v1"
+	// )
+	//
+	// func main() {
+	// 	fmt.Println(corev1.Pod{}, util.Version)
+	// }
+	// fmt: "" => "" (std)
+	// example.com/project/pkg/util: "" => "" (project)
+	// k8s.io/api/core/v1: "" => "corev1" (external)
+}
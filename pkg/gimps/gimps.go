@@ -5,6 +5,7 @@ package gimps
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/format"
@@ -12,6 +13,7 @@ import (
 	"go/printer"
 	"go/token"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -23,6 +25,21 @@ type importMetadata struct {
 	Comment *ast.CommentGroup
 	Alias   string
 	Package string
+	Set     string
+
+	// OriginalAlias is the alias as found in the source file, before
+	// any alias rules have been applied.
+	OriginalAlias string
+
+	// OriginalIndex is the position among all imports in the source file,
+	// OriginalLine the line number and OriginalGroup the index of the block
+	// of imports (separated by empty lines or import declarations).
+	OriginalIndex int
+	OriginalLine  int
+	OriginalGroup int
+
+	// Renames is the number of identifiers rewritten to use the new alias.
+	Renames int
 }
 
 func (m *importMetadata) Statement() string {
@@ -47,39 +64,99 @@ func (p *importPosition) IsInRange(comment *ast.CommentGroup) bool {
 	return false
 }
 
-// Execute is for revise imports and format the code
-func Execute(config *Config, filePath string, aliaser *Aliaser) ([]byte, bool, error) {
-	setDefaults(config)
-
+// Execute is for revise imports and format the code. It reads the given
+// file and formats it using Format.
+func Execute(config *Config, filePath string, aliaser *Aliaser) (*Result, error) {
 	originalContent, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
+	return Format(config, filePath, originalContent, aliaser)
+}
+
+// Format revises the imports of the given source code and formats it. The
+// file itself is never read, the filePath is only used to load the package's
+// dependencies and go.mod through the aliaser's resolver (or from disk, if
+// no aliaser is given). The aliaser must have been created for the same
+// config. It is safe to call Format concurrently.
+func Format(config *Config, filePath string, originalContent []byte, aliaser *Aliaser) (*Result, error) {
+	// the aliaser holds the prepared alias and classification rules
+	if aliaser != nil && aliaser.config != config {
+		return nil, errors.New("the aliaser has been created for a different config")
+	}
+
+	// do not modify the caller's config, it might be shared between goroutines
+	configCopy := *config
+	config = &configCopy
+	setDefaults(config)
+
 	fset := token.NewFileSet()
 
 	file, err := parser.ParseFile(fset, "", originalContent, parser.ParseComments)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to parse file: %v", err)
+		return nil, fmt.Errorf("failed to parse file: %v", err)
 	}
 
 	// determine the imports used in the file
-	imports, err := parseImports(file, filePath)
+	imports, err := parseImports(fset, file)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to parse imports: %v", err)
+		return nil, fmt.Errorf("failed to parse imports: %v", err)
+	}
+
+	importDecls := len(getImportDecls(file))
+
+	// remove unused imports first, so no aliases need to be calculated for them
+	var deps PackageNameResolver
+	if aliaser != nil {
+		deps = aliaser.deps
+	} else {
+		deps = NewDependencyCache()
+	}
+
+	removed := []*importMetadata{}
+	if config.RemoveUnusedImports {
+		removed = removeUnusedImports(file, filePath, imports, deps)
 	}
 
 	// re-calculate aliases early, but only spend the effort if some rules
 	// are configured
+	conflicts := []AliasConflict{}
 	if aliaser != nil {
-		err = aliaser.RewriteFile(file, filePath, imports)
+		conflicts, err = aliaser.RewriteFile(fset, file, filePath, imports)
+		if errors.Is(err, errSkipFile) {
+			// the file stays unchanged, so its original imports must be checked
+			violations, err := aliaser.lintSource(filePath, originalContent)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check aliases: %v", err)
+			}
+
+			return &Result{
+				Output:     originalContent,
+				Imports:    []ImportResult{},
+				Removed:    []ImportResult{},
+				Violations: violations,
+				Conflicts:  conflicts,
+			}, nil
+		}
+
 		if err != nil {
-			return nil, false, fmt.Errorf("failed to rewrite import aliases: %v", err)
+			return nil, fmt.Errorf("failed to rewrite import aliases: %w", err)
 		}
 	}
 
 	// apply classification rules to group the imports into sets
-	importSets := groupImports(config, imports)
+	var classifier *Classifier
+	if aliaser != nil {
+		classifier = aliaser.classifier
+	} else if classifier, err = newConfigClassifier(config); err != nil {
+		return nil, err
+	}
+
+	importSets, err := groupImports(config, classifier, deps, filePath, imports)
+	if err != nil {
+		return nil, err
+	}
 
 	// merge import statements into a single one
 	combineImportDecls(file)
@@ -92,21 +169,43 @@ func Execute(config *Config, filePath string, aliaser *Aliaser) ([]byte, bool, e
 
 	fixedImportsContent, err := generateFile(fset, file)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to generate code: %v", err)
+		return nil, fmt.Errorf("failed to generate code: %v", err)
 	}
 
 	formattedContent, err := format.Source(fixedImportsContent)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to format code: %v", err)
+		return nil, fmt.Errorf("failed to format code: %v", err)
+	}
+
+	result, err := buildResult(formattedContent, importSets, imports, removed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse formatted code: %v", err)
+	}
+
+	result.Changed = !bytes.Equal(originalContent, formattedContent)
+	result.Combined = importDecls > 1
+	result.Conflicts = append(result.Conflicts, conflicts...)
+
+	if aliaser != nil {
+		result.Violations, err = aliaser.lint(file, filePath, imports)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check aliases: %v", err)
+		}
 	}
 
-	return formattedContent, !bytes.Equal(originalContent, formattedContent), nil
+	return result, nil
 }
 
-func parseImports(file *ast.File, filePath string) (map[string]*importMetadata, error) {
+func parseImports(fset *token.FileSet, file *ast.File) (map[string]*importMetadata, error) {
 	metadata := map[string]*importMetadata{}
+	index := 0
+	group := -1
 
 	for _, importDecl := range getImportDecls(file) {
+		// every import declaration starts a new group
+		group++
+		previousEnd := 0
+
 		for _, spec := range importDecl.Specs {
 			importSpec := spec.(*ast.ImportSpec)
 			key := importSpec.Path.Value
@@ -119,29 +218,79 @@ func parseImports(file *ast.File, filePath string) (map[string]*importMetadata,
 				key = fmt.Sprintf("%s %s", alias, importSpec.Path.Value)
 			}
 
+			// an empty line between two imports starts a new group
+			start := fset.Position(importSpec.Pos()).Line
+			if importSpec.Doc != nil {
+				start = fset.Position(importSpec.Doc.Pos()).Line
+			}
+
+			if previousEnd > 0 && start > previousEnd+1 {
+				group++
+			}
+
+			previousEnd = fset.Position(importSpec.End()).Line
+
 			// key is a quoted string, like `"fmt"` or `yaml "gopkg.in/yaml.v3"`
 			metadata[key] = &importMetadata{
-				Doc:     importSpec.Doc,
-				Comment: importSpec.Comment,
-				Package: pkg,
-				Alias:   alias,
+				Doc:           importSpec.Doc,
+				Comment:       importSpec.Comment,
+				Package:       pkg,
+				Alias:         alias,
+				OriginalAlias: alias,
+				OriginalIndex: index,
+				OriginalLine:  fset.Position(importSpec.Pos()).Line,
+				OriginalGroup: group,
 			}
+
+			index++
 		}
 	}
 
 	return metadata, nil
 }
 
+// newConfigClassifier validates the classification settings of the config,
+// which must have its defaults set, and returns a classifier for them.
+func newConfigClassifier(config *Config) (*Classifier, error) {
+	if config.FallbackSet != "" && !slices.Contains(config.ImportOrder, config.FallbackSet) {
+		return nil, fmt.Errorf("fallback set %q is not listed in the import order", config.FallbackSet)
+	}
+
+	return NewClassifier(ClassifierOptions{
+		ProjectName:  config.ProjectName,
+		Sets:         config.Sets,
+		StdDetection: config.StdDetection,
+		Matching:     config.Matching,
+	})
+}
+
 // groupImports takes all the imports of a file and matches them against
-// the configured classification rules. It then returns a list of import
-// sets.
-func groupImports(config *Config, imports map[string]*importMetadata) []importSet {
+// the classifier's rules. It then returns a list of import sets. Imports
+// from sets that are not part of the import order are moved into the
+// fallback set; if none is configured, an error is returned instead of
+// silently dropping the imports. Sets with subgroups are split into
+// multiple consecutive import sets.
+func groupImports(config *Config, classifier *Classifier, resolver PackageNameResolver, filePath string, imports map[string]*importMetadata) ([]importSet, error) {
+	classifier, err := classifier.forFile(filePath, resolver)
+	if err != nil {
+		return nil, err
+	}
+
 	sets := map[string]importSet{}
-	classifier := NewClassifier(config.ProjectName, config.Sets)
 
 	for imprt, metadata := range imports {
 		setName := classifier.ClassifyImport(metadata.Package)
 
+		if !slices.Contains(config.ImportOrder, setName) {
+			if config.FallbackSet == "" {
+				return nil, fmt.Errorf("import %q belongs to set %q, which is not listed in the import order", metadata.Package, setName)
+			}
+
+			setName = config.FallbackSet
+		}
+
+		metadata.Set = setName
+
 		if _, ok := sets[setName]; !ok {
 			sets[setName] = importSet{}
 		}
@@ -153,11 +302,11 @@ func groupImports(config *Config, imports map[string]*importMetadata) []importSe
 	for _, setName := range config.ImportOrder {
 		if set, ok := sets[setName]; ok {
 			sort.Strings(set)
-			result = append(result, set)
+			result = append(result, classifier.subgroups(setName, set, imports)...)
 		}
 	}
 
-	return result
+	return result, nil
 }
 
 // combineImportDecls will return combined import declarations to single declaration
@@ -262,7 +411,9 @@ func rebuildImports(tok token.Token, imports map[string]*importMetadata, importS
 	return specs
 }
 
-// ???
+// clearImportDocs removes all comments inside the import declaration from
+// the file. Doc and line comments of each import have already been copied
+// into the rebuilt import specs and would otherwise be printed twice.
 func clearImportDocs(file *ast.File, importPositions []*importPosition) {
 	importsComments := make([]*ast.CommentGroup, 0, len(file.Comments))
 
@@ -308,22 +459,46 @@ func removeEmptyImportNode(file *ast.File) {
 }
 
 // importWithComment appends a possible comment to the import statement,
-// i.e. turning `foo "gopkg.in/foo/v2"` into `foo "gopkg.in/foo/v2" // my comment`
+// i.e. turning `foo "gopkg.in/foo/v2"` into `foo "gopkg.in/foo/v2" // my comment`.
+// Doc comments (comments in the line(s) directly above the import) are prepended,
+// so that they move along with the import when it is regrouped.
 func importWithComment(imprt string, imports map[string]*importMetadata) string {
-	var comment string
-	if metadata, ok := imports[imprt]; ok && metadata.Comment != nil && len(metadata.Comment.List) > 0 {
-		// comments can be normal comments ("// foo bar") or special comments like "//foo bar";
-		// special comments will return an empty string if Text() is called.
-		// TODO: use TrimSpace() ? Can this be a multiline comment?
-		formatted := strings.ReplaceAll(metadata.Comment.Text(), "\n", "")
-		if len(formatted) == 0 {
-			comment = metadata.Comment.List[0].Text
-		} else {
-			comment = fmt.Sprintf("// %s", formatted)
+	var (
+		doc     string
+		comment string
+	)
+
+	if metadata, ok := imports[imprt]; ok {
+		if metadata.Doc != nil && len(metadata.Doc.List) > 0 {
+			doc = docComment(metadata.Doc)
+		}
+
+		if metadata.Comment != nil && len(metadata.Comment.List) > 0 {
+			// comments can be normal comments ("// foo bar") or special comments like "//foo bar";
+			// special comments will return an empty string if Text() is called.
+			// TODO: use TrimSpace() ? Can this be a multiline comment?
+			formatted := strings.ReplaceAll(metadata.Comment.Text(), "\n", "")
+			if len(formatted) == 0 {
+				comment = metadata.Comment.List[0].Text
+			} else {
+				comment = fmt.Sprintf("// %s", formatted)
+			}
 		}
 	}
 
-	return strings.TrimSpace(fmt.Sprintf("%s %s", imprt, comment))
+	return strings.TrimSpace(fmt.Sprintf("%s\n%s %s", doc, imprt, comment))
+}
+
+// docComment returns the raw comments, one per line. Unlike Text(), this
+// keeps the comment markers and does not strip directives like "//nolint".
+// The indentation is fixed later on when the code is formatted.
+func docComment(doc *ast.CommentGroup) string {
+	lines := make([]string, len(doc.List))
+	for i, comment := range doc.List {
+		lines[i] = comment.Text
+	}
+
+	return strings.Join(lines, "\n")
 }
 
 // generateFile creates Go source code for the given token set and file.
@@ -6,6 +6,7 @@ package gimps
 import (
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,19 +37,15 @@ func TestExecute(t *testing.T) {
 
 		t.Run(name, func(t *testing.T) {
 			config := loadTestConfig(t, testcase)
+			goFile := copyTestcase(t, testcase)
 
-			inputFile := filepath.Join(testcase, "main.go.input")
-			goFile := filepath.Join(testcase, "main.go")
-			os.Rename(inputFile, goFile)
-			defer os.Rename(goFile, inputFile)
-
-			aliaser, err := NewAliaser(config.ProjectName, config.AliasRules)
+			aliaser, err := NewAliaser(&config.Config)
 			assertTestError(t, err, config.ExpectedAliaserError)
 			if config.ExpectedAliaserError != nil {
 				return
 			}
 
-			output, _, err := Execute(&config.Config, goFile, aliaser)
+			result, err := Execute(&config.Config, goFile, aliaser)
 			assertTestError(t, err, config.ExpectedExecuteError)
 			if config.ExpectedExecuteError != nil {
 				return
@@ -57,7 +54,7 @@ func TestExecute(t *testing.T) {
 			expectedFile := filepath.Join(testcase, "main.go.expected")
 			expected, err := os.ReadFile(expectedFile)
 			require.Nil(t, err)
-			assert.Equal(t, string(expected), string(output))
+			assert.Equal(t, string(expected), string(result.Output))
 		})
 	}
 }
@@ -90,3 +87,474 @@ func loadTestConfig(t *testing.T, testcase string) *testConfig {
 
 	return c
 }
+
+// copyTestcase copies all files of the testcase into a temporary directory,
+// so that neither renaming the input file nor loading packages (which can
+// update the go.mod, depending on GOFLAGS) modifies the testdata. The path
+// to the copied main.go is returned.
+func copyTestcase(t *testing.T, testcase string) string {
+	dir := t.TempDir()
+
+	entries, err := os.ReadDir(testcase)
+	require.Nil(t, err)
+
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(testcase, entry.Name()))
+		require.Nil(t, err)
+
+		name := entry.Name()
+		if name == "main.go.input" {
+			name = "main.go"
+		}
+
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), content, 0644))
+	}
+
+	return filepath.Join(dir, "main.go")
+}
+
+func TestExecuteConcurrently(t *testing.T) {
+	testcase, err := filepath.Abs("testdata/aliases-basic")
+	require.Nil(t, err)
+
+	config := loadTestConfig(t, testcase)
+	goFile := copyTestcase(t, testcase)
+
+	expected, err := os.ReadFile(filepath.Join(testcase, "main.go.expected"))
+	require.Nil(t, err)
+
+	// share the same config and aliaser (and therefore the dependency cache)
+	aliaser, err := NewAliaser(&config.Config)
+	require.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result, err := Execute(&config.Config, goFile, aliaser)
+			assert.Nil(t, err)
+			if err == nil {
+				assert.Equal(t, string(expected), string(result.Output))
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestFormatResult(t *testing.T) {
+	source := `package main
+
+import (
+	"log"
+	"k8s.io/api/core/v1"
+
+	"fmt"
+)
+
+import "os"
+
+func main() {
+	fmt.Println(v1.Pod{}, v1.Node{}, os.Args)
+	log.Println(v1.Namespace{})
+}
+`
+
+	config := &Config{
+		ProjectName: "go.xrstf.de/gimps/test",
+		AliasRules: []AliasRule{{
+			Name:       "k8s-api",
+			Expression: `^k8s.io/api/([a-z0-9-]+)/(v[a-z0-9-]+)$`,
+			Alias:      "$1$2",
+		}},
+	}
+
+	aliaser, err := NewAliaserWithResolver(config, PackageNames{"k8s.io/api/core/v1": "v1"})
+	require.Nil(t, err)
+
+	result, err := Format(config, "main.go", []byte(source), aliaser)
+	require.Nil(t, err)
+
+	assert.True(t, result.Changed)
+	assert.True(t, result.Combined)
+	assert.True(t, result.Regrouped)
+	assert.True(t, result.Resorted)
+	assert.True(t, result.Realiased)
+	assert.Empty(t, result.Removed)
+
+	expected := []ImportResult{
+		{Package: "fmt", Set: SetStd, OldIndex: 2, NewIndex: 0, OldLine: 7, NewLine: 4},
+		{Package: "log", Set: SetStd, OldIndex: 0, NewIndex: 1, OldLine: 4, NewLine: 5},
+		{Package: "os", Set: SetStd, OldIndex: 3, NewIndex: 2, OldLine: 10, NewLine: 6},
+		{Package: "k8s.io/api/core/v1", NewAlias: "corev1", Set: SetExternal, OldIndex: 1, NewIndex: 3, OldLine: 5, NewLine: 8, Renames: 3},
+	}
+
+	assert.Equal(t, expected, result.Imports)
+}
+
+func TestFormatResultWithoutChanges(t *testing.T) {
+	source := `package main
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/api/core/v1"
+)
+
+func main() {
+	fmt.Println(v1.Pod{}, os.Args)
+}
+`
+
+	config := &Config{ProjectName: "go.xrstf.de/gimps/test"}
+
+	result, err := Format(config, "main.go", []byte(source), nil)
+	require.Nil(t, err)
+
+	assert.False(t, result.Changed)
+	assert.False(t, result.Combined)
+	assert.False(t, result.Regrouped)
+	assert.False(t, result.Resorted)
+	assert.False(t, result.Realiased)
+}
+
+func TestFormatViolations(t *testing.T) {
+	source := `package main
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	yml "gopkg.in/yaml.v3"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func main() {
+	fmt.Println(v1.Pod{}, metav1.Time{}, yml.Marshal, ctrl.Log)
+}
+`
+
+	config := &Config{
+		ProjectName: "go.xrstf.de/gimps/test",
+		ForbiddenAliases: []ForbiddenAlias{{
+			Name:  "no-bare-versions",
+			Alias: `^v[0-9]+$`,
+		}, {
+			Name:   "metav1-for-meta-only",
+			Alias:  `^metav1$`,
+			Except: `^k8s.io/apimachinery/pkg/apis/meta/v1$`,
+		}},
+		RequiredAliases: []AliasRule{{
+			Name:       "no-yaml-alias",
+			Type:       AliasRuleTypeUnalias,
+			Expression: `^gopkg.in/yaml.v3$`,
+		}, {
+			Name:       "controller-runtime",
+			Expression: `^sigs.k8s.io/controller-runtime$`,
+			Alias:      "ctrlruntime",
+		}},
+	}
+
+	aliaser, err := NewAliaserWithResolver(config, PackageNames{})
+	require.Nil(t, err)
+
+	result, err := Format(config, "main.go", []byte(source), aliaser)
+	require.Nil(t, err)
+
+	expected := []Violation{
+		{
+			Rule:    "no-bare-versions",
+			Package: "k8s.io/api/core/v1",
+			Alias:   "v1",
+			Line:    6,
+			Message: `alias "v1" for "k8s.io/api/core/v1" is forbidden by rule no-bare-versions`,
+		},
+		{
+			Rule:    "no-yaml-alias",
+			Package: "gopkg.in/yaml.v3",
+			Alias:   "yml",
+			Line:    8,
+			Message: `"gopkg.in/yaml.v3" must not be aliased (rule no-yaml-alias)`,
+		},
+		{
+			Rule:    "controller-runtime",
+			Package: "sigs.k8s.io/controller-runtime",
+			Alias:   "ctrl",
+			Line:    9,
+			Message: `"sigs.k8s.io/controller-runtime" must be imported as "ctrlruntime" (rule controller-runtime)`,
+		},
+	}
+
+	assert.Equal(t, expected, result.Violations)
+}
+
+func TestFormatViolationsWithoutAlias(t *testing.T) {
+	source := `package main
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func main() {
+	fmt.Println(v1.Pod{})
+}
+`
+
+	config := &Config{
+		ProjectName: "go.xrstf.de/gimps/test",
+		ForbiddenAliases: []ForbiddenAlias{{
+			Name:   "no-bare-versions",
+			Alias:  `^v[0-9]+$`,
+			Except: `^k8s.io/apimachinery/`,
+		}},
+	}
+
+	aliaser, err := NewAliaserWithResolver(config, PackageNames{})
+	require.Nil(t, err)
+
+	result, err := Format(config, "main.go", []byte(source), aliaser)
+	require.Nil(t, err)
+
+	expected := []Violation{
+		{
+			Rule:    "no-bare-versions",
+			Package: "k8s.io/api/core/v1",
+			Line:    6,
+			Message: `package name "v1" of "k8s.io/api/core/v1" is forbidden by rule no-bare-versions, an alias is required`,
+		},
+	}
+
+	assert.Equal(t, expected, result.Violations)
+}
+
+func TestFormatWithUnknownPackageName(t *testing.T) {
+	// the package is named clientv3, not client as its import path suggests
+	source := `package main
+
+import (
+	"context"
+
+	"go.etcd.io/etcd/client/v3"
+)
+
+func main() {
+	clientv3.New(context.Background())
+}
+`
+
+	config := &Config{
+		ProjectName:         "go.xrstf.de/gimps/test",
+		RemoveUnusedImports: true,
+	}
+
+	aliaser, err := NewAliaserWithResolver(config, PackageNames{})
+	require.Nil(t, err)
+
+	// imports with unknown names must never be removed
+	result, err := Format(config, "main.go", []byte(source), aliaser)
+	require.Nil(t, err)
+	assert.Equal(t, source, string(result.Output))
+	assert.Empty(t, result.Removed)
+
+	// nor renamed, as the old name is unknown
+	config.AliasRules = []AliasRule{{
+		Name:       "etcd",
+		Expression: `^go.etcd.io/etcd/client/v3$`,
+		Alias:      "etcdclient",
+	}}
+
+	aliaser, err = NewAliaserWithResolver(config, PackageNames{})
+	require.Nil(t, err)
+
+	_, err = Format(config, "main.go", []byte(source), aliaser)
+	require.ErrorIs(t, err, ErrUnknownPackage)
+
+	// with the actual name, the import can be renamed
+	aliaser, err = NewAliaserWithResolver(config, PackageNames{"go.etcd.io/etcd/client/v3": "clientv3"})
+	require.Nil(t, err)
+
+	result, err = Format(config, "main.go", []byte(source), aliaser)
+	require.Nil(t, err)
+	assert.Contains(t, string(result.Output), `etcdclient "go.etcd.io/etcd/client/v3"`)
+	assert.Contains(t, string(result.Output), `etcdclient.New(context.Background())`)
+}
+
+// inMemoryModule resolves package names and the go.mod file without
+// touching the filesystem.
+type inMemoryModule struct {
+	PackageNames
+	goMod string
+}
+
+func (m inMemoryModule) GetGoMod(_ string) (string, []byte, error) {
+	return "go.mod", []byte(m.goMod), nil
+}
+
+func TestFormatWithGoModResolver(t *testing.T) {
+	source := `package main
+
+import (
+	"fmt"
+	"github.com/foo/direct"
+	"github.com/foo/indirect"
+)
+
+func main() {
+	fmt.Println(direct.A, indirect.B)
+}
+`
+
+	config := &Config{
+		ProjectName: "go.xrstf.de/gimps/test",
+		ImportOrder: []string{SetStd, "direct", SetExternal},
+		Sets: []Set{{
+			Name:  "direct",
+			GoMod: GoModDirect,
+		}},
+	}
+
+	resolver := inMemoryModule{
+		PackageNames: PackageNames{},
+		goMod: `module go.xrstf.de/gimps/test
+
+require (
+	github.com/foo/direct v1.0.0
+	github.com/foo/indirect v1.0.0 // indirect
+)
+`,
+	}
+
+	aliaser, err := NewAliaserWithResolver(config, resolver)
+	require.Nil(t, err)
+
+	// the file does not exist, so the go.mod can only come from the resolver
+	result, err := Format(config, "/does/not/exist/main.go", []byte(source), aliaser)
+	require.Nil(t, err)
+
+	expected := `package main
+
+import (
+	"fmt"
+
+	"github.com/foo/direct"
+
+	"github.com/foo/indirect"
+)
+
+func main() {
+	fmt.Println(direct.A, indirect.B)
+}
+`
+	assert.Equal(t, expected, string(result.Output))
+
+	// PackageNames cannot provide a go.mod file
+	aliaser, err = NewAliaserWithResolver(config, PackageNames{})
+	require.Nil(t, err)
+
+	_, err = Format(config, "/does/not/exist/main.go", []byte(source), aliaser)
+	require.EqualError(t, err, `failed to load go.mod for "/does/not/exist/main.go": the package name resolver cannot provide go.mod files`)
+}
+
+func TestFormatConflicts(t *testing.T) {
+	source := `package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func main() {
+	fmt.Println(log.Println)
+}
+`
+
+	config := &Config{
+		ProjectName:    "go.xrstf.de/gimps/test",
+		AliasConflicts: AliasConflictsSkipRule,
+		AliasRules: []AliasRule{{
+			Name:       "everything",
+			Expression: `^(fmt|log)$`,
+			Alias:      "std",
+		}},
+	}
+
+	aliaser, err := NewAliaserWithResolver(config, PackageNames{})
+	require.Nil(t, err)
+
+	result, err := Format(config, "main.go", []byte(source), aliaser)
+	require.Nil(t, err)
+
+	assert.False(t, result.Changed)
+	assert.Equal(t, []AliasConflict{{
+		Alias:    "std",
+		Packages: []string{"fmt", "log"},
+		Strategy: AliasConflictsSkipRule,
+	}}, result.Conflicts)
+}
+
+func TestFormatSkippedFileViolations(t *testing.T) {
+	source := `package main
+
+import (
+	"fmt"
+	"log"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func main() {
+	fmt.Println(log.Println, v1.Pod{})
+}
+`
+
+	config := &Config{
+		ProjectName:    "go.xrstf.de/gimps/test",
+		AliasConflicts: AliasConflictsSkipFile,
+		AliasRules: []AliasRule{{
+			Name:       "everything",
+			Expression: `^(fmt|log)$`,
+			Alias:      "std",
+		}},
+		ForbiddenAliases: []ForbiddenAlias{{
+			Name:  "no-v1",
+			Alias: `^v1$`,
+		}},
+	}
+
+	aliaser, err := NewAliaserWithResolver(config, PackageNames{})
+	require.Nil(t, err)
+
+	result, err := Format(config, "main.go", []byte(source), aliaser)
+	require.Nil(t, err)
+
+	// the file is left unchanged, but must still be checked
+	assert.False(t, result.Changed)
+	assert.Equal(t, source, string(result.Output))
+	assert.Equal(t, []Violation{{
+		Rule:    "no-v1",
+		Package: "k8s.io/api/core/v1",
+		Alias:   "v1",
+		Line:    7,
+		Message: `alias "v1" for "k8s.io/api/core/v1" is forbidden by rule no-v1`,
+	}}, result.Violations)
+}
+
+func TestFormatWithAliaserForDifferentConfig(t *testing.T) {
+	config := newTestConfig("go.xrstf.de/gimps/test")
+
+	aliaser, err := NewAliaserWithResolver(config, PackageNames{})
+	require.Nil(t, err)
+
+	// even an identical copy could be modified independently later on
+	other := *config
+
+	_, err = Format(&other, "main.go", []byte("package main\n"), aliaser)
+	require.EqualError(t, err, "the aliaser has been created for a different config")
+}
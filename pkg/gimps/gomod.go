@@ -0,0 +1,250 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	// GoModDirect matches imports from modules that are required directly
+	// in the go.mod file.
+	GoModDirect = "direct"
+
+	// GoModIndirect matches imports from modules that are marked as
+	// "// indirect" in the go.mod file.
+	GoModIndirect = "indirect"
+
+	// GoModReplaced matches imports from modules that are replaced by a
+	// local directory, like `replace example.com/foo => ../foo`.
+	GoModReplaced = "replaced"
+)
+
+// GoModSources are all valid values for Set.GoMod.
+var GoModSources = []string{
+	GoModDirect,
+	GoModIndirect,
+	GoModReplaced,
+}
+
+// goModule is a module required by a go.mod file.
+type goModule struct {
+	path     string
+	indirect bool
+	replaced bool
+}
+
+// is returns true if the module belongs to the given GoModSources value.
+func (m *goModule) is(source string) bool {
+	switch source {
+	case GoModDirect:
+		return !m.indirect
+	case GoModIndirect:
+		return m.indirect
+	case GoModReplaced:
+		return m.replaced
+	default:
+		return false
+	}
+}
+
+// goModFile are the requirements of a go.mod file, sorted by descending
+// module path length, so that the first matching module is the most
+// specific one.
+type goModFile struct {
+	modules []goModule
+}
+
+// owningModule returns the required module that provides the package,
+// or nil if the package does not belong to any required module.
+func (f *goModFile) owningModule(pkg string) *goModule {
+	for i, module := range f.modules {
+		if pkg == module.path || strings.HasPrefix(pkg, module.path+"/") {
+			return &f.modules[i]
+		}
+	}
+
+	return nil
+}
+
+func parseGoMod(filename string, content []byte) (*goModFile, error) {
+	parsed, err := modfile.Parse(filename, content, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// a replacement without version applies to all versions of the module
+	localReplacements := map[string]struct{}{}
+	for _, replace := range parsed.Replace {
+		if modfile.IsDirectoryPath(replace.New.Path) {
+			localReplacements[replace.Old.Path] = struct{}{}
+		}
+	}
+
+	result := &goModFile{}
+	for _, require := range parsed.Require {
+		_, replaced := localReplacements[require.Mod.Path]
+
+		result.modules = append(result.modules, goModule{
+			path:     require.Mod.Path,
+			indirect: require.Indirect,
+			replaced: replaced,
+		})
+	}
+
+	sort.SliceStable(result.modules, func(i, j int) bool {
+		return len(result.modules[i].path) > len(result.modules[j].path)
+	})
+
+	return result, nil
+}
+
+// GoModResolver is an optional interface for a PackageNameResolver that can
+// also provide go.mod files. They are needed by sets based on the go.mod and
+// by subgrouping imports by module. DependencyCache reads them from disk.
+type GoModResolver interface {
+	// GetGoMod returns the filename and content of the go.mod file of the
+	// module that contains the given file.
+	GetGoMod(filePath string) (filename string, content []byte, err error)
+}
+
+var _ GoModResolver = &DependencyCache{}
+
+type goModCacheEntry struct {
+	once    sync.Once
+	content []byte
+	goMod   *goModFile
+	err     error
+}
+
+// GetGoMod finds the go.mod file of the module that contains the given file
+// and reads it. Each go.mod file is only read once.
+func (c *DependencyCache) GetGoMod(filePath string) (string, []byte, error) {
+	filename, entry, err := c.getGoMod(filePath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return filename, entry.content, entry.err
+}
+
+func (c *DependencyCache) getGoMod(filePath string) (string, *goModCacheEntry, error) {
+	filename, err := findGoMod(filePath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	c.lock.Lock()
+	entry, ok := c.goMods[filename]
+	if !ok {
+		entry = &goModCacheEntry{}
+		c.goMods[filename] = entry
+	}
+	c.lock.Unlock()
+
+	entry.once.Do(func() {
+		entry.content, entry.err = os.ReadFile(filename)
+		if entry.err == nil {
+			entry.goMod, entry.err = parseGoMod(filename, entry.content)
+		}
+	})
+
+	return filename, entry, nil
+}
+
+// loadGoMod returns the parsed go.mod file of the module that contains the
+// given file, as provided by the resolver. go.mod files from a
+// DependencyCache are parsed only once.
+func loadGoMod(resolver PackageNameResolver, filePath string) (*goModFile, error) {
+	switch r := resolver.(type) {
+	case *DependencyCache:
+		_, entry, err := r.getGoMod(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		return entry.goMod, entry.err
+
+	case GoModResolver:
+		filename, content, err := r.GetGoMod(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		return parseGoMod(filename, content)
+
+	default:
+		return nil, errors.New("the package name resolver cannot provide go.mod files")
+	}
+}
+
+// findGoMod returns the path to the go.mod file in the directory of the
+// given file or the closest of its parent directories.
+func findGoMod(filePath string) (string, error) {
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		filename := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("no go.mod found")
+		}
+
+		dir = parent
+	}
+}
+
+// forFile returns a copy of the classifier that uses the go.mod of the given
+// file, if any of the sets needs it. Subgrouping by module works without a
+// go.mod as well, so failing to load it is only an error for go.mod sets.
+func (c *Classifier) forFile(filePath string, resolver PackageNameResolver) (*Classifier, error) {
+	required := false
+	for _, set := range c.sets {
+		if set.goMod != "" {
+			required = true
+			break
+		}
+	}
+
+	optional := false
+	for _, mode := range c.subgroupBy {
+		if mode == SubgroupByModule {
+			optional = true
+			break
+		}
+	}
+
+	if !required && !optional {
+		return c, nil
+	}
+
+	goMod, err := loadGoMod(resolver, filePath)
+	if err != nil {
+		if required {
+			return nil, fmt.Errorf("failed to load go.mod for %q: %w", filePath, err)
+		}
+
+		return c, nil
+	}
+
+	fileClassifier := *c
+	fileClassifier.goMod = goMod
+
+	return &fileClassifier, nil
+}
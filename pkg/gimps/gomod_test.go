@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"testing"
+)
+
+func TestOwningModule(t *testing.T) {
+	goMod, err := parseGoMod("go.mod", []byte(`
+module example.com/project
+
+go 1.22
+
+require (
+	github.com/kubermatic/machine-controller v1.60.0
+	github.com/kubermatic/machine-controller/sdk v1.60.0 // indirect
+	k8s.io/api v0.31.0
+)
+
+replace github.com/kubermatic/machine-controller => ./forks/machine-controller
+
+replace k8s.io/api => k8s.io/api v0.30.0
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse go.mod: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		importPath string
+		expected   *goModule
+	}{
+		{
+			name:       "module root package",
+			importPath: "k8s.io/api",
+			expected:   &goModule{path: "k8s.io/api"},
+		},
+		{
+			name:       "locally replaced module",
+			importPath: "github.com/kubermatic/machine-controller/pkg/apis",
+			expected:   &goModule{path: "github.com/kubermatic/machine-controller", replaced: true},
+		},
+		{
+			name:       "longest module path wins",
+			importPath: "github.com/kubermatic/machine-controller/sdk/providerconfig",
+			expected:   &goModule{path: "github.com/kubermatic/machine-controller/sdk", indirect: true},
+		},
+		{
+			name:       "module path is no prefix of another path element",
+			importPath: "k8s.io/apimachinery/pkg/types",
+			expected:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := goMod.owningModule(tt.importPath)
+
+			switch {
+			case tt.expected == nil && module != nil:
+				t.Errorf("Expected no module, but got %+v", *module)
+			case tt.expected != nil && module == nil:
+				t.Errorf("Expected %+v, but got no module", *tt.expected)
+			case tt.expected != nil && *module != *tt.expected:
+				t.Errorf("Expected %+v, but got %+v", *tt.expected, *module)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+)
+
+// ForbiddenAlias is a lint rule that reports all imports using an alias that
+// matches the Alias expression. Packages matching the optional Except
+// expression are allowed to use such aliases.
+type ForbiddenAlias struct {
+	Name         string         `yaml:"name"`
+	Alias        string         `yaml:"alias"`
+	aliasRegexp  *regexp.Regexp `yaml:"-"`
+	Except       string         `yaml:"except"`
+	exceptRegexp *regexp.Regexp `yaml:"-"`
+}
+
+// Violation is an import that does not adhere to the configured
+// forbidden or required aliases. Violations are only reported, never fixed.
+type Violation struct {
+	// Rule is the name of the violated rule.
+	Rule string
+	// Package is the import path, like "k8s.io/api/core/v1".
+	Package string
+	// Alias is the alias of the import, after all alias rules have been applied.
+	Alias string
+	// Line is the line number of the import in the original source code.
+	Line int
+	// Message describes the violation.
+	Message string
+}
+
+func compileForbiddenAliases(rules []ForbiddenAlias) error {
+	for i, rule := range rules {
+		expr, err := regexp.Compile(rule.Alias)
+		if err != nil {
+			return fmt.Errorf("invalid alias expression in forbidden alias %d: %v", i+1, err)
+		}
+
+		rules[i].aliasRegexp = expr
+
+		if rule.Except != "" {
+			expr, err := regexp.Compile(rule.Except)
+			if err != nil {
+				return fmt.Errorf("invalid except expression in forbidden alias %d: %v", i+1, err)
+			}
+
+			rules[i].exceptRegexp = expr
+		}
+	}
+
+	return nil
+}
+
+// lintSource checks the imports of unmodified source code against the
+// forbidden and required aliases.
+func (a *Aliaser) lintSource(filePath string, source []byte) ([]Violation, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, "", source, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %v", err)
+	}
+
+	imports, err := parseImports(fset, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse imports: %v", err)
+	}
+
+	return a.lint(file, filePath, imports)
+}
+
+// lint checks the (already rewritten) imports against the forbidden and
+// required aliases. Violations are sorted by line.
+func (a *Aliaser) lint(file *ast.File, filePath string, imports map[string]*importMetadata) ([]Violation, error) {
+	buildTags := parseBuildTags(file)
+	violations := []Violation{}
+
+	for _, metadata := range imports {
+		// imports without an alias are referred to by their package name
+		name := metadata.Alias
+		if name == "" && len(a.forbiddenAliases) > 0 {
+			name = a.effectivePackageName(filePath, buildTags, metadata.Package)
+		}
+
+		for _, rule := range a.forbiddenAliases {
+			if !rule.aliasRegexp.MatchString(name) {
+				continue
+			}
+
+			if rule.exceptRegexp != nil && rule.exceptRegexp.MatchString(metadata.Package) {
+				continue
+			}
+
+			message := fmt.Sprintf("alias %q for %q is forbidden by rule %s", name, metadata.Package, rule.Name)
+			if metadata.Alias == "" {
+				message = fmt.Sprintf("package name %q of %q is forbidden by rule %s, an alias is required", name, metadata.Package, rule.Name)
+			}
+
+			violations = append(violations, Violation{
+				Rule:    rule.Name,
+				Package: metadata.Package,
+				Alias:   metadata.Alias,
+				Line:    metadata.OriginalLine,
+				Message: message,
+			})
+		}
+
+		// blank and dot imports do not introduce a name that could be required
+		if metadata.Alias == "_" || metadata.Alias == "." {
+			continue
+		}
+
+		// like alias rules, only the first matching rule applies
+		for _, rule := range a.requiredAliases {
+			if !rule.regexp.MatchString(metadata.Package) {
+				continue
+			}
+
+			var message string
+
+			if rule.Type == AliasRuleTypeUnalias {
+				if metadata.Alias != "" {
+					message = fmt.Sprintf("%q must not be aliased (rule %s)", metadata.Package, rule.Name)
+				}
+			} else {
+				required, err := rule.generateAlias(metadata.Package, a.packageNameFunc(filePath, buildTags, metadata.Package))
+				if err != nil {
+					return nil, fmt.Errorf("failed to apply required alias %s to %q: %v", rule.Name, metadata.Package, err)
+				}
+
+				if metadata.Alias != required {
+					message = fmt.Sprintf("%q must be imported as %q (rule %s)", metadata.Package, required, rule.Name)
+				}
+			}
+
+			if message != "" {
+				violations = append(violations, Violation{
+					Rule:    rule.Name,
+					Package: metadata.Package,
+					Alias:   metadata.Alias,
+					Line:    metadata.OriginalLine,
+					Message: message,
+				})
+			}
+
+			break
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Line != violations[j].Line {
+			return violations[i].Line < violations[j].Line
+		}
+
+		return violations[i].Rule < violations[j].Rule
+	})
+
+	return violations, nil
+}
+
+// effectivePackageName returns the name an unaliased import is referred to
+// by. If the package cannot be resolved, its name is guessed based on the
+// import path.
+func (a *Aliaser) effectivePackageName(filePath string, buildTags string, pkg string) string {
+	name, err := a.deps.GetPackageName(filePath, buildTags, pkg)
+	if err != nil || name == "" {
+		return guessPackageName(pkg)
+	}
+
+	return name
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/incu6us/goimports-reviser/v3/pkg/std"
+)
+
+// PackageNames is a PackageNameResolver that does not touch the filesystem
+// and is meant for formatting code in memory, e.g. in code generators. The
+// map contains import paths and their package names. Apart from the standard
+// library, all other packages are unknown, as guessing their names based on
+// the import path could make gimps remove used imports.
+type PackageNames map[string]string
+
+var _ PackageNameResolver = PackageNames{}
+
+// ErrUnknownPackage is returned by PackageNames for import paths that are
+// not part of the map.
+var ErrUnknownPackage = errors.New("unknown package")
+
+func (n PackageNames) GetPackageName(_ string, _ string, packagePath string) (string, error) {
+	if name, ok := n[packagePath]; ok {
+		return name, nil
+	}
+
+	// the standard library always follows the naming conventions
+	if _, ok := std.StdPackages[packagePath]; ok {
+		return guessPackageName(packagePath), nil
+	}
+
+	return "", fmt.Errorf("%w %q, its name must be added to the PackageNames", ErrUnknownPackage, packagePath)
+}
+
+// majorVersion matches major version suffixes of Go modules; v0 and v1
+// are not valid suffixes, but common package names (e.g. "k8s.io/api/core/v1").
+var majorVersion = regexp.MustCompile(`^v([2-9]|[1-9][0-9]+)$`)
+
+// guessPackageName returns the most likely package name for an import path,
+// e.g. "doublestar" for "github.com/bmatcuk/doublestar/v4", "yaml" for
+// "gopkg.in/yaml.v3" or "errors" for "github.com/go-errors/errors".
+func guessPackageName(packagePath string) string {
+	name := path.Base(packagePath)
+
+	if majorVersion.MatchString(name) {
+		if dir := path.Dir(packagePath); dir != "." {
+			name = path.Base(dir)
+		}
+	}
+
+	// gopkg.in/yaml.v3
+	if strings.HasPrefix(packagePath, "gopkg.in/") {
+		if idx := strings.LastIndex(name, ".v"); idx > 0 {
+			name = name[:idx]
+		}
+	}
+
+	name = strings.TrimPrefix(name, "go-")
+
+	// cut off everything that is not valid in an identifier,
+	// like "-go" in "github.com/foo/bar-go"
+	if idx := strings.IndexFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); idx > 0 {
+		name = name[:idx]
+	}
+
+	return name
+}
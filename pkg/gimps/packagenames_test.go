@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"testing"
+)
+
+func TestGuessPackageName(t *testing.T) {
+	tests := []struct {
+		importPath string
+		expected   string
+	}{
+		{importPath: "fmt", expected: "fmt"},
+		{importPath: "net/http", expected: "http"},
+		{importPath: "math/rand/v2", expected: "rand"},
+		{importPath: "github.com/bmatcuk/doublestar/v4", expected: "doublestar"},
+		{importPath: "gopkg.in/yaml.v3", expected: "yaml"},
+		{importPath: "gopkg.in/src-d/go-git.v4", expected: "git"},
+		{importPath: "k8s.io/api/core/v1", expected: "v1"},
+		{importPath: "k8s.io/api/networking/v1beta1", expected: "v1beta1"},
+		{importPath: "github.com/foo/bar-go", expected: "bar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.importPath, func(t *testing.T) {
+			result := guessPackageName(tt.importPath)
+			if result != tt.expected {
+				t.Errorf("guessPackageName() returned %q, but wanted %q", result, tt.expected)
+			}
+		})
+	}
+}
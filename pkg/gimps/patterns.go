@@ -0,0 +1,308 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+
+	doublestar "github.com/bmatcuk/doublestar/v4"
+)
+
+const (
+	// patternNegation marks a pattern that excludes packages from a set.
+	patternNegation = "!"
+
+	// patternRegexPrefix marks a pattern as a Go regular expression
+	// instead of a glob.
+	patternRegexPrefix = "regex:"
+)
+
+const (
+	// SetMatchingFirst assigns imports to the first set with a matching
+	// pattern. This is the default.
+	SetMatchingFirst = "first"
+
+	// SetMatchingSpecific assigns imports to the set with the most specific
+	// matching pattern, regardless of the order of sets. If multiple sets
+	// are equally specific, the first one wins.
+	SetMatchingSpecific = "specific"
+)
+
+// SetMatchingModes are all valid values for Config.Matching.
+var SetMatchingModes = []string{
+	SetMatchingFirst,
+	SetMatchingSpecific,
+}
+
+// patternMatcher is a precompiled set pattern.
+type patternMatcher struct {
+	negated     bool
+	glob        string
+	regexp      *regexp.Regexp
+	specificity specificity
+}
+
+// specificity describes how specific a pattern is. A pattern is more
+// specific than another if it has a longer literal prefix, i.e. it fixes
+// more of the import path, or if both prefixes are equally long and only
+// the first pattern matches exactly one import path.
+type specificity struct {
+	// prefix is the literal text every matching import path starts with,
+	// without a trailing slash.
+	prefix string
+	// exact is true if the pattern matches only the prefix itself.
+	exact bool
+}
+
+// compare returns a positive number if s is more specific than other,
+// a negative number if it is less specific and 0 if both are equal.
+func (s specificity) compare(other specificity) int {
+	if len(s.prefix) != len(other.prefix) {
+		return len(s.prefix) - len(other.prefix)
+	}
+
+	switch {
+	case s.exact && !other.exact:
+		return 1
+	case !s.exact && other.exact:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func globSpecificity(glob string) specificity {
+	meta := strings.IndexAny(glob, `*?[{\`)
+	if meta < 0 {
+		return specificity{prefix: glob, exact: true}
+	}
+
+	return specificity{prefix: strings.TrimSuffix(glob[:meta], "/")}
+}
+
+// regexpSpecificity only considers expressions anchored at the start of the
+// import path; all others could match anywhere and have no literal prefix.
+func regexpSpecificity(expr string) specificity {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return specificity{}
+	}
+
+	re = re.Simplify()
+	if re.Op != syntax.OpConcat || len(re.Sub) < 2 || re.Sub[0].Op != syntax.OpBeginText {
+		return specificity{}
+	}
+
+	literal := re.Sub[1]
+	if literal.Op != syntax.OpLiteral || literal.Flags&syntax.FoldCase != 0 {
+		return specificity{}
+	}
+
+	prefix := string(literal.Rune)
+	exact := len(re.Sub) == 3 && re.Sub[2].Op == syntax.OpEndText
+
+	if !exact {
+		prefix = strings.TrimSuffix(prefix, "/")
+	}
+
+	return specificity{prefix: prefix, exact: exact}
+}
+
+// compilePattern parses a set pattern, which is either a doublestar glob
+// or, if prefixed with "regex:", a regular expression. Patterns prefixed
+// with "!" exclude matching packages from the set.
+func compilePattern(pattern string) (patternMatcher, error) {
+	matcher := patternMatcher{}
+
+	if IsNegatedSetPattern(pattern) {
+		matcher.negated = true
+		pattern = strings.TrimPrefix(pattern, patternNegation)
+	}
+
+	if expr, ok := strings.CutPrefix(pattern, patternRegexPrefix); ok {
+		if expr == "" {
+			return matcher, errors.New("empty regular expression")
+		}
+
+		compiled, err := regexp.Compile(expr)
+		if err != nil {
+			return matcher, err
+		}
+
+		matcher.regexp = compiled
+		matcher.specificity = regexpSpecificity(expr)
+
+		return matcher, nil
+	}
+
+	if pattern == "" {
+		return matcher, errors.New("empty pattern")
+	}
+
+	if !doublestar.ValidatePattern(pattern) {
+		return matcher, errors.New("invalid glob")
+	}
+
+	matcher.glob = pattern
+	matcher.specificity = globSpecificity(pattern)
+
+	return matcher, nil
+}
+
+func (m patternMatcher) matches(pkg string) bool {
+	if m.regexp != nil {
+		return m.regexp.MatchString(pkg)
+	}
+
+	// the pattern has been validated when it was compiled
+	return doublestar.MatchUnvalidated(m.glob, pkg)
+}
+
+// ValidateSetPattern checks that a single set pattern can be compiled.
+func ValidateSetPattern(pattern string) error {
+	_, err := compilePattern(pattern)
+	return err
+}
+
+// IsNegatedSetPattern returns true if the pattern excludes packages
+// from a set.
+func IsNegatedSetPattern(pattern string) bool {
+	return strings.HasPrefix(pattern, patternNegation)
+}
+
+// setMatcher is a set with precompiled patterns.
+type setMatcher struct {
+	name     string
+	includes []patternMatcher
+	excludes []patternMatcher
+	goMod    string
+}
+
+func compileSet(set Set) (setMatcher, error) {
+	matcher := setMatcher{
+		name:  set.Name,
+		goMod: set.GoMod,
+	}
+
+	if set.GoMod != "" && !slices.Contains(GoModSources, set.GoMod) {
+		return matcher, fmt.Errorf("invalid goMod %q", set.GoMod)
+	}
+
+	for _, pattern := range set.Patterns {
+		compiled, err := compilePattern(pattern)
+		if err != nil {
+			return matcher, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		if compiled.negated {
+			matcher.excludes = append(matcher.excludes, compiled)
+		} else {
+			matcher.includes = append(matcher.includes, compiled)
+		}
+	}
+
+	if len(matcher.includes) == 0 && matcher.goMod == "" {
+		return matcher, errors.New("only exclusions but no patterns to include packages")
+	}
+
+	return matcher, nil
+}
+
+// match checks if the package matches at least one of the set's patterns
+// or belongs to a module from the set's go.mod source, but matches none of
+// its exclusions. If so, the specificity of the most specific match is
+// returned. Modules from the go.mod are as specific as a "<module>/**"
+// pattern.
+func (m setMatcher) match(pkg string, goMod *goModFile) (specificity, bool) {
+	var (
+		best    specificity
+		matched bool
+	)
+
+	for _, include := range m.includes {
+		if include.matches(pkg) && (!matched || include.specificity.compare(best) > 0) {
+			best = include.specificity
+			matched = true
+		}
+	}
+
+	if m.goMod != "" && goMod != nil {
+		if module := goMod.owningModule(pkg); module != nil && module.is(m.goMod) {
+			moduleSpecificity := specificity{prefix: module.path}
+			if !matched || moduleSpecificity.compare(best) > 0 {
+				best = moduleSpecificity
+				matched = true
+			}
+		}
+	}
+
+	if !matched {
+		return best, false
+	}
+
+	for _, exclude := range m.excludes {
+		if exclude.matches(pkg) {
+			return best, false
+		}
+	}
+
+	return best, true
+}
+
+// SetOverlap describes two equally specific patterns of different sets that
+// can match the same imports. With SetMatchingSpecific, such imports are
+// assigned to the earlier set, so the order of sets still matters for them.
+type SetOverlap struct {
+	// Set and Pattern are the indexes of the earlier set and its pattern.
+	Set     int
+	Pattern int
+	// OtherSet and OtherPattern are the indexes of the later set and its pattern.
+	OtherSet     int
+	OtherPattern int
+}
+
+// FindSetOverlaps returns all overlapping patterns of the given sets.
+// Invalid and negated patterns are ignored.
+func FindSetOverlaps(sets []Set) []SetOverlap {
+	type indexedPattern struct {
+		set     int
+		pattern int
+		matcher patternMatcher
+	}
+
+	patterns := []indexedPattern{}
+	for i, set := range sets {
+		for j, pattern := range set.Patterns {
+			matcher, err := compilePattern(pattern)
+			if err != nil || matcher.negated {
+				continue
+			}
+
+			patterns = append(patterns, indexedPattern{set: i, pattern: j, matcher: matcher})
+		}
+	}
+
+	overlaps := []SetOverlap{}
+	for i, a := range patterns {
+		for _, b := range patterns[i+1:] {
+			if a.set == b.set || a.matcher.specificity != b.matcher.specificity {
+				continue
+			}
+
+			overlaps = append(overlaps, SetOverlap{
+				Set:          a.set,
+				Pattern:      a.pattern,
+				OtherSet:     b.set,
+				OtherPattern: b.pattern,
+			})
+		}
+	}
+
+	return overlaps
+}
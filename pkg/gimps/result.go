@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"go/parser"
+	"go/token"
+	"sort"
+)
+
+// Result describes the outcome of formatting a single file.
+type Result struct {
+	// Output is the formatted source code.
+	Output []byte
+	// Changed is true if the output differs from the original source code.
+	Changed bool
+	// Imports contains the file's imports, in the order they appear in the output.
+	Imports []ImportResult
+	// Removed contains all imports that have been removed because they were unused.
+	Removed []ImportResult
+
+	// Combined is true if multiple import declarations were merged into one.
+	Combined bool
+	// Regrouped is true if imports were moved between groups, i.e. the
+	// empty lines separating blocks of imports changed.
+	Regrouped bool
+	// Resorted is true if the order of the imports changed.
+	Resorted bool
+	// Realiased is true if the alias of at least one import changed.
+	Realiased bool
+
+	// Violations contains all imports that violate the forbidden or
+	// required aliases, sorted by line. They do not affect Changed.
+	Violations []Violation
+
+	// Conflicts contains all conflicting aliases that have been resolved
+	// according to the configured strategy. If the strategy is to skip the
+	// file, the Output is the original source code and Imports is empty.
+	Conflicts []AliasConflict
+}
+
+// ImportResult describes a single import of a formatted file.
+type ImportResult struct {
+	// Package is the import path, like "k8s.io/api/core/v1".
+	Package string
+	// OldAlias is the alias as found in the original source code.
+	OldAlias string
+	// NewAlias is the alias after all alias rules have been applied.
+	NewAlias string
+	// Set is the name of the set the import has been classified into.
+	Set string
+
+	// OldIndex is the zero-based position among all imports in the original
+	// source code, NewIndex the position in the output (-1 for removed imports).
+	OldIndex int
+	NewIndex int
+	// OldLine and NewLine are the line numbers of the import in the original
+	// source code and the output (0 for removed imports).
+	OldLine int
+	NewLine int
+
+	// Renames is the number of identifiers that were rewritten to use the new alias.
+	Renames int
+}
+
+// buildResult describes the imports of the formatted code. The new positions
+// are determined by parsing the output, as go/format can still reorder
+// imports within a group.
+func buildResult(output []byte, importSets []importSet, imports map[string]*importMetadata, removed []*importMetadata) (*Result, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, "", output, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	newGroups := map[string]int{}
+	for i, set := range importSets {
+		for _, imprt := range set {
+			newGroups[imprt] = i
+		}
+	}
+
+	result := &Result{
+		Output:     output,
+		Imports:    []ImportResult{},
+		Removed:    []ImportResult{},
+		Violations: []Violation{},
+		Conflicts:  []AliasConflict{},
+	}
+
+	for newIndex, spec := range file.Imports {
+		key := spec.Path.Value
+		if spec.Name != nil {
+			key = spec.Name.Name + " " + key
+		}
+
+		metadata, ok := imports[key]
+		if !ok {
+			continue
+		}
+
+		result.Imports = append(result.Imports, ImportResult{
+			Package:  metadata.Package,
+			OldAlias: metadata.OriginalAlias,
+			NewAlias: metadata.Alias,
+			Set:      metadata.Set,
+			OldIndex: metadata.OriginalIndex,
+			NewIndex: newIndex,
+			OldLine:  metadata.OriginalLine,
+			NewLine:  fset.Position(spec.Pos()).Line,
+			Renames:  metadata.Renames,
+		})
+
+		if metadata.OriginalAlias != metadata.Alias {
+			result.Realiased = true
+		}
+	}
+
+	for _, metadata := range removed {
+		result.Removed = append(result.Removed, ImportResult{
+			Package:  metadata.Package,
+			OldAlias: metadata.OriginalAlias,
+			OldIndex: metadata.OriginalIndex,
+			NewIndex: -1,
+			OldLine:  metadata.OriginalLine,
+		})
+	}
+
+	result.Resorted = isResorted(result.Imports)
+	result.Regrouped = isRegrouped(imports, newGroups)
+
+	return result, nil
+}
+
+// isResorted checks whether the imports (in their new order) are still in
+// the same relative order as before.
+func isResorted(imports []ImportResult) bool {
+	return !sort.SliceIsSorted(imports, func(i, j int) bool {
+		return imports[i].OldIndex < imports[j].OldIndex
+	})
+}
+
+// isRegrouped checks whether two imports that were in the same group are
+// now in different groups, or vice versa.
+func isRegrouped(imports map[string]*importMetadata, newGroups map[string]int) bool {
+	keys := make([]string, 0, len(imports))
+	for key := range imports {
+		keys = append(keys, key)
+	}
+
+	for i, a := range keys {
+		for _, b := range keys[i+1:] {
+			sameOld := imports[a].OriginalGroup == imports[b].OriginalGroup
+			sameNew := newGroups[a] == newGroups[b]
+
+			if sameOld != sameNew {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/incu6us/goimports-reviser/v3/pkg/std"
+)
+
+const (
+	// StdDetectionStatic uses a static list of standard library packages,
+	// which might not contain packages added in recent Go releases. This
+	// is the default.
+	StdDetectionStatic = "static"
+
+	// StdDetectionToolchain asks the active Go toolchain for the list of
+	// standard library packages. The list is cached on disk per GOROOT
+	// and Go version. If the toolchain cannot be used, imports are
+	// detected by the static list first and the heuristic second.
+	StdDetectionToolchain = "toolchain"
+
+	// StdDetectionHeuristic treats all imports whose first path element
+	// does not contain a dot as standard library packages, unless they
+	// belong to the project.
+	StdDetectionHeuristic = "heuristic"
+)
+
+// StdDetectionModes are all valid values for Config.StdDetection.
+var StdDetectionModes = []string{
+	StdDetectionStatic,
+	StdDetectionToolchain,
+	StdDetectionHeuristic,
+}
+
+var (
+	toolchainStdOnce     sync.Once
+	toolchainStdPackages map[string]struct{}
+
+	// goCommand runs the go command with the given arguments and returns
+	// its standard output.
+	goCommand = func(args ...string) ([]byte, error) {
+		return exec.Command("go", args...).Output()
+	}
+
+	// stdCacheDir returns the directory in which the lists of standard
+	// library packages are cached.
+	stdCacheDir = os.UserCacheDir
+)
+
+// getToolchainStdPackages returns the standard library packages of the
+// active Go toolchain. They are determined only once per process, nil is
+// returned if the toolchain could not be used.
+func getToolchainStdPackages() map[string]struct{} {
+	toolchainStdOnce.Do(func() {
+		packages, err := loadToolchainStdPackages()
+		if err == nil {
+			toolchainStdPackages = packages
+		}
+	})
+
+	return toolchainStdPackages
+}
+
+func loadToolchainStdPackages() (map[string]struct{}, error) {
+	env, err := goCommand("env", "GOROOT", "GOVERSION")
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine Go toolchain: %w", err)
+	}
+
+	cacheFile := stdCacheFile(env)
+	if cacheFile != "" {
+		if cached, err := os.ReadFile(cacheFile); err == nil {
+			return parseStdPackages(cached), nil
+		}
+	}
+
+	output, err := goCommand("list", "std")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list standard library packages: %w", err)
+	}
+
+	// caching is best effort; write to a temporary file first, so that
+	// concurrent gimps processes never read a partial list
+	if cacheFile != "" {
+		if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err == nil {
+			tmpFile := fmt.Sprintf("%s.%d.tmp", cacheFile, os.Getpid())
+			if err := os.WriteFile(tmpFile, output, 0644); err == nil {
+				if err := os.Rename(tmpFile, cacheFile); err != nil {
+					os.Remove(tmpFile)
+				}
+			}
+		}
+	}
+
+	return parseStdPackages(output), nil
+}
+
+// stdCacheFile returns the path to the cached list of standard library
+// packages for the given output of `go env GOROOT GOVERSION`, or an empty
+// string if there is no cache directory.
+func stdCacheFile(goEnv []byte) string {
+	cacheDir, err := stdCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	hash := sha256.Sum256(bytes.TrimSpace(goEnv))
+
+	return filepath.Join(cacheDir, "gimps", "std-"+hex.EncodeToString(hash[:8])+".txt")
+}
+
+func parseStdPackages(list []byte) map[string]struct{} {
+	packages := map[string]struct{}{}
+
+	for _, line := range strings.Split(string(list), "\n") {
+		if pkg := strings.TrimSpace(line); pkg != "" {
+			packages[pkg] = struct{}{}
+		}
+	}
+
+	return packages
+}
+
+// IsStdImport returns true if the package belongs to the standard library,
+// according to the configured detection mode.
+func (c *Classifier) IsStdImport(pkg string) bool {
+	switch c.stdDetection {
+	case StdDetectionToolchain:
+		if packages := getToolchainStdPackages(); packages != nil {
+			_, ok := packages[pkg]
+			return ok
+		}
+
+		// without a toolchain, the static list is only missing new packages,
+		// which the heuristic can still catch
+		return isStaticStdImport(pkg) || c.isHeuristicStdImport(pkg)
+
+	case StdDetectionHeuristic:
+		return c.isHeuristicStdImport(pkg)
+
+	default:
+		return isStaticStdImport(pkg)
+	}
+}
+
+func isStaticStdImport(pkg string) bool {
+	_, ok := std.StdPackages[pkg]
+	return ok
+}
+
+func (c *Classifier) isHeuristicStdImport(pkg string) bool {
+	firstElement, _, _ := strings.Cut(pkg, "/")
+	return !strings.Contains(firstElement, ".") && !c.IsProjectImport(pkg)
+}
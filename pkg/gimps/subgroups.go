@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"strings"
+)
+
+const (
+	// SubgroupByNone keeps all imports of a set in a single block. This is
+	// the default.
+	SubgroupByNone = "none"
+
+	// SubgroupByModule separates imports from different modules by empty
+	// lines. Modules are determined using the go.mod file; imports that
+	// cannot be attributed to a module are grouped by domain instead.
+	SubgroupByModule = "module"
+
+	// SubgroupByDomain separates imports from different hosts, like
+	// "github.com" or "k8s.io", by empty lines.
+	SubgroupByDomain = "domain"
+)
+
+// SubgroupModes are all valid values for Set.SubgroupBy.
+var SubgroupModes = []string{
+	SubgroupByNone,
+	SubgroupByModule,
+	SubgroupByDomain,
+}
+
+// subgroupKey returns the key that determines the subgroup of the package
+// within its set. Packages whose first path element contains no dot (like
+// the standard library) share the empty key when grouping by domain.
+func (c *Classifier) subgroupKey(mode string, pkg string) string {
+	if mode == SubgroupByModule {
+		if c.IsProjectImport(pkg) {
+			return c.projectName
+		}
+
+		if c.goMod != nil {
+			if module := c.goMod.owningModule(pkg); module != nil {
+				return module.path
+			}
+		}
+	}
+
+	domain, _, _ := strings.Cut(pkg, "/")
+	if !strings.Contains(domain, ".") {
+		return ""
+	}
+
+	return domain
+}
+
+// subgroups splits the (sorted) imports of a set into consecutive
+// subgroups, according to the set's SubgroupBy mode. Subgroups are ordered
+// by their first import, imports keep their order within each subgroup.
+func (c *Classifier) subgroups(setName string, set importSet, imports map[string]*importMetadata) []importSet {
+	mode := c.subgroupBy[setName]
+	if mode == "" || mode == SubgroupByNone || len(set) < 2 {
+		return []importSet{set}
+	}
+
+	keys := []string{}
+	byKey := map[string]importSet{}
+
+	for _, imprt := range set {
+		key := c.subgroupKey(mode, imports[imprt].Package)
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		byKey[key] = append(byKey[key], imprt)
+	}
+
+	subgroups := make([]importSet, len(keys))
+	for i, key := range keys {
+		subgroups[i] = byKey[key]
+	}
+
+	return subgroups
+}
@@ -0,0 +1,5 @@
+package main
+
+// nethttp is declared in a different file of the
+// same package than the import that is renamed.
+var nethttp = "collision"
@@ -0,0 +1,8 @@
+module go.xrstf.de/gimps/test
+
+go 1.16
+
+require (
+	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
+)
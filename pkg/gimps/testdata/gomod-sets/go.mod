@@ -0,0 +1,16 @@
+module go.xrstf.de/gimps/test
+
+go 1.16
+
+require (
+	github.com/kubermatic/machine-controller v1.60.0
+	github.com/spf13/pflag v1.0.6
+	k8s.io/api v0.31.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kubermatic/machine-controller/sdk v0.0.0-00010101000000-000000000000 // indirect
+)
+
+replace github.com/kubermatic/machine-controller => ../machine-controller
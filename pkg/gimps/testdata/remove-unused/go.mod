@@ -0,0 +1,3 @@
+module go.xrstf.de/gimps/test
+
+go 1.16
@@ -0,0 +1,11 @@
+module go.xrstf.de/gimps/test
+
+go 1.16
+
+require (
+	github.com/kubermatic/machine-controller v1.60.0
+	github.com/kubermatic/machine-controller/sdk v1.60.0
+	github.com/spf13/pflag v1.0.6
+	k8s.io/api v0.31.0
+	k8s.io/apimachinery v0.31.0
+)
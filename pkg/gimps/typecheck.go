@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// typedFile is the result of type-checking a single file as part of
+// its package.
+type typedFile struct {
+	pkg  *types.Package
+	info *types.Info
+}
+
+type packagesCacheEntry struct {
+	once     sync.Once
+	packages []*packages.Package
+	err      error
+}
+
+// getPackages loads the packages (including test packages) in the given
+// directory, including type information for all of their dependencies.
+func (c *DependencyCache) getPackages(directory string, buildTags string) ([]*packages.Package, error) {
+	key := dependencyCacheKey{
+		directory: directory,
+		buildTags: buildTags,
+	}
+
+	c.lock.Lock()
+	entry, ok := c.packages[key]
+	if !ok {
+		entry = &packagesCacheEntry{}
+		c.packages[key] = entry
+	}
+	c.lock.Unlock()
+
+	entry.once.Do(func() {
+		cfg := &packages.Config{
+			Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedTypes,
+			Dir:   directory,
+			Tests: true,
+		}
+
+		if buildTags != "" {
+			cfg.BuildFlags = []string{"-tags", buildTags}
+		}
+
+		entry.packages, entry.err = packages.Load(cfg, ".")
+		if entry.err != nil {
+			entry.err = fmt.Errorf("failed to load packages: %v", entry.err)
+		}
+	})
+
+	return entry.packages, entry.err
+}
+
+// reservedPositions is the number of positions at the start of the file set
+// for cached package files that are not used by any of them. A file that is
+// type-checked together with cached files must fit into this space, so that
+// its positions (from its own file set) do not overlap with theirs.
+const reservedPositions = 1 << 30
+
+// packageFiles are the parsed Go files of all packages in a directory.
+type packageFiles struct {
+	fset  *token.FileSet
+	files map[string]*ast.File
+	errs  map[string]error
+}
+
+type packageFilesCacheEntry struct {
+	once  sync.Once
+	files *packageFiles
+}
+
+// getPackageFiles parses the Go files of the given packages (which must have
+// been loaded from the directory) once, so that formatting multiple files of
+// the same package does not parse the other files over and over again.
+func (c *DependencyCache) getPackageFiles(directory string, buildTags string, pkgs []*packages.Package) *packageFiles {
+	key := dependencyCacheKey{
+		directory: directory,
+		buildTags: buildTags,
+	}
+
+	c.lock.Lock()
+	entry, ok := c.packageFiles[key]
+	if !ok {
+		entry = &packageFilesCacheEntry{}
+		c.packageFiles[key] = entry
+	}
+	c.lock.Unlock()
+
+	entry.once.Do(func() {
+		result := &packageFiles{
+			fset:  token.NewFileSet(),
+			files: map[string]*ast.File{},
+			errs:  map[string]error{},
+		}
+
+		result.fset.AddFile("", -1, reservedPositions)
+
+		for _, pkg := range pkgs {
+			for _, filename := range pkg.GoFiles {
+				if _, ok := result.files[filename]; ok {
+					continue
+				}
+
+				if _, ok := result.errs[filename]; ok {
+					continue
+				}
+
+				f, err := parser.ParseFile(result.fset, filename, nil, 0)
+				if err != nil {
+					result.errs[filename] = err
+					continue
+				}
+
+				result.files[filename] = f
+			}
+		}
+
+		entry.files = result
+	})
+
+	return entry.files
+}
+
+// typeCheck type-checks the package the given file belongs to. The given
+// syntax tree is used instead of the file on disk (which might contain
+// different code or not exist at all), all other files of the package are
+// only parsed once. Type errors are ignored, so that broken code still
+// yields as much type information as possible.
+func (c *DependencyCache) typeCheck(file *ast.File, filePath string, buildTags string) (*typedFile, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine absolute path: %v", err)
+	}
+
+	pkgs, err := c.getPackages(filepath.Dir(absPath), buildTags)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := findPackage(pkgs, absPath, file.Name.Name)
+	if pkg == nil {
+		return nil, fmt.Errorf("no package %q found in %q", file.Name.Name, filepath.Dir(absPath))
+	}
+
+	if int(file.FileEnd) >= reservedPositions {
+		return nil, fmt.Errorf("%q is too large to be type-checked", filePath)
+	}
+
+	packageFiles := c.getPackageFiles(filepath.Dir(absPath), buildTags, pkgs)
+
+	files := []*ast.File{file}
+	for _, filename := range pkg.GoFiles {
+		if filename == absPath {
+			continue
+		}
+
+		if err := packageFiles.errs[filename]; err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", filename, err)
+		}
+
+		files = append(files, packageFiles.files[filename])
+	}
+
+	info := &types.Info{
+		Defs:   map[*ast.Ident]types.Object{},
+		Uses:   map[*ast.Ident]types.Object{},
+		Scopes: map[ast.Node]*types.Scope{},
+	}
+
+	conf := types.Config{
+		Importer:    packageImporter(pkg.Imports),
+		FakeImportC: true,
+		Error:       func(error) {},
+	}
+
+	// the positions of the given file are only valid in its own file set,
+	// but they do not overlap with those of the other files; type errors
+	// are ignored, so positions are never resolved to file names anyway
+	typesPkg, _ := conf.Check(pkg.PkgPath, packageFiles.fset, files, info)
+
+	return &typedFile{
+		pkg:  typesPkg,
+		info: info,
+	}, nil
+}
+
+// findPackage returns the package that contains the given file. Files that
+// belong to both a package and its test variant are resolved to the regular
+// package. If the file is not part of any package (e.g. because it does not
+// exist on disk), the package with the given name is returned instead.
+func findPackage(pkgs []*packages.Package, filename string, packageName string) *packages.Package {
+	var candidate *packages.Package
+
+	for _, pkg := range pkgs {
+		// skip the generated test main packages
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+
+		for _, f := range pkg.GoFiles {
+			if f == filename {
+				if pkg.ID == pkg.PkgPath {
+					return pkg
+				}
+
+				candidate = pkg
+			}
+		}
+	}
+
+	if candidate != nil {
+		return candidate
+	}
+
+	for _, pkg := range pkgs {
+		if pkg.Name == packageName && !strings.HasSuffix(pkg.ID, ".test") {
+			if pkg.ID == pkg.PkgPath {
+				return pkg
+			}
+
+			candidate = pkg
+		}
+	}
+
+	return candidate
+}
+
+type packageImporter map[string]*packages.Package
+
+func (i packageImporter) Import(path string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+
+	pkg, ok := i[path]
+	if !ok || pkg.Types == nil {
+		return nil, fmt.Errorf("package %q is not a dependency", path)
+	}
+
+	return pkg.Types, nil
+}
+
+// isLocal returns true if the object has been declared inside a function,
+// i.e. not in the universe, package or file scope.
+func isLocal(obj types.Object, pkg *types.Package) bool {
+	scope := obj.Parent()
+
+	return scope != nil && scope != types.Universe && scope != pkg.Scope() && scope.Parent() != pkg.Scope()
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeCheckReusesPackageFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod":   "module go.xrstf.de/gimps/test\n\ngo 1.16\n",
+		"main.go":  "package main\n\nfunc main() { helper() }\n",
+		"other.go": "package main\n\nfunc helper() { value++ }\n",
+		"types.go": "package main\n\nvar value int\n",
+	}
+
+	for name, content := range files {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	cache := NewDependencyCache()
+
+	for _, name := range []string{"main.go", "other.go"} {
+		filePath := filepath.Join(dir, name)
+
+		file, err := parser.ParseFile(token.NewFileSet(), filePath, files[name], 0)
+		require.Nil(t, err)
+
+		typed, err := cache.typeCheck(file, filePath, "")
+		require.Nil(t, err)
+
+		// all identifiers must be resolved, including those declared in other files
+		for ident, obj := range typed.info.Uses {
+			require.NotNil(t, obj, "identifier %q in %s was not resolved", ident.Name, name)
+		}
+
+		require.NotNil(t, typed.pkg.Scope().Lookup("helper"))
+		require.NotNil(t, typed.pkg.Scope().Lookup("value"))
+	}
+
+	// both files share the same parsed package files
+	require.Len(t, cache.packageFiles, 1)
+
+	for _, entry := range cache.packageFiles {
+		require.Len(t, entry.files.files, 3)
+	}
+}
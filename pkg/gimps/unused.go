@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package gimps
+
+import (
+	"go/ast"
+	"sort"
+)
+
+// removeUnusedImports removes all imports from the metadata map that are never
+// referenced in the file and returns the removed imports. Blank, dot and cgo
+// imports are always kept, as are imports whose package name cannot be
+// determined (for example because the package is only a dependency on a
+// different platform), to not accidentally break the file.
+func removeUnusedImports(file *ast.File, filePath string, imports map[string]*importMetadata, deps PackageNameResolver) []*importMetadata {
+	usedNames := map[string]struct{}{}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		sel, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		// identifiers that resolve to a declaration in the file (i.e. a
+		// variable shadowing the package name) are not package references
+		if ident, ok := sel.X.(*ast.Ident); ok && ident.Obj == nil {
+			usedNames[ident.Name] = struct{}{}
+		}
+
+		return true
+	})
+
+	buildTags := parseBuildTags(file)
+	removed := []*importMetadata{}
+
+	for key, metadata := range imports {
+		name := metadata.Alias
+
+		switch {
+		case name == "_" || name == ".":
+			continue
+
+		case metadata.Package == "C":
+			continue
+
+		case name == "":
+			var err error
+
+			name, err = deps.GetPackageName(filePath, buildTags, metadata.Package)
+			if err != nil {
+				continue
+			}
+		}
+
+		if _, used := usedNames[name]; !used {
+			removed = append(removed, metadata)
+			delete(imports, key)
+		}
+	}
+
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].Package < removed[j].Package
+	})
+
+	return removed
+}
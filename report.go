@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"path/filepath"
+
+	"go.xrstf.de/gimps/pkg/gimps"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+)
+
+// jsonReport is the machine-readable result of a gimps run.
+type jsonReport struct {
+	Files []fileReport `json:"files"`
+}
+
+type fileReport struct {
+	// Path is relative to the module root and always uses forward slashes.
+	Path    string `json:"path"`
+	Changed bool   `json:"changed"`
+	// Skipped is either "excluded" or "generated".
+	Skipped string `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Changes lists the kinds of changes, like "combined", "regrouped",
+	// "resorted" or "realiased".
+	Changes []string       `json:"changes,omitempty"`
+	Imports []importReport `json:"imports,omitempty"`
+	Removed []importReport `json:"removed,omitempty"`
+	// Violations lists imports that violate the forbidden or required aliases.
+	Violations []violationReport `json:"violations,omitempty"`
+	// Conflicts lists the resolved alias conflicts.
+	Conflicts []conflictReport `json:"conflicts,omitempty"`
+}
+
+type importReport struct {
+	Package  string `json:"package"`
+	OldAlias string `json:"oldAlias,omitempty"`
+	NewAlias string `json:"newAlias,omitempty"`
+	Set      string `json:"set,omitempty"`
+	OldLine  int    `json:"oldLine"`
+	NewLine  int    `json:"newLine,omitempty"`
+	Renames  int    `json:"renames,omitempty"`
+}
+
+type violationReport struct {
+	Line    int    `json:"line"`
+	Rule    string `json:"rule"`
+	Package string `json:"package"`
+	Alias   string `json:"alias,omitempty"`
+	Message string `json:"message"`
+}
+
+type conflictReport struct {
+	Alias    string   `json:"alias"`
+	Packages []string `json:"packages"`
+	Strategy string   `json:"strategy"`
+}
+
+func (r *jsonReport) Add(res fileResult) {
+	file := fileReport{
+		Path:    filepath.ToSlash(res.relPath),
+		Skipped: res.skipped,
+	}
+
+	if res.err != nil {
+		file.Error = res.err.Error()
+	}
+
+	if res.result != nil {
+		result := res.result
+		file.Changed = result.Changed
+
+		changes := []struct {
+			kind    string
+			changed bool
+		}{
+			{"combined", result.Combined},
+			{"regrouped", result.Regrouped},
+			{"resorted", result.Resorted},
+			{"realiased", result.Realiased},
+			{"removed", len(result.Removed) > 0},
+		}
+
+		for _, change := range changes {
+			if change.changed {
+				file.Changes = append(file.Changes, change.kind)
+			}
+		}
+
+		for _, imprt := range result.Imports {
+			file.Imports = append(file.Imports, newImportReport(imprt))
+		}
+
+		for _, imprt := range result.Removed {
+			file.Removed = append(file.Removed, newImportReport(imprt))
+		}
+
+		for _, violation := range result.Violations {
+			file.Violations = append(file.Violations, violationReport{
+				Line:    violation.Line,
+				Rule:    violation.Rule,
+				Package: violation.Package,
+				Alias:   violation.Alias,
+				Message: violation.Message,
+			})
+		}
+
+		for _, conflict := range result.Conflicts {
+			file.Conflicts = append(file.Conflicts, conflictReport{
+				Alias:    conflict.Alias,
+				Packages: conflict.Packages,
+				Strategy: conflict.Strategy,
+			})
+		}
+	}
+
+	r.Files = append(r.Files, file)
+}
+
+func newImportReport(imprt gimps.ImportResult) importReport {
+	return importReport{
+		Package:  imprt.Package,
+		OldAlias: imprt.OldAlias,
+		NewAlias: imprt.NewAlias,
+		Set:      imprt.Set,
+		OldLine:  imprt.OldLine,
+		NewLine:  imprt.NewLine,
+		Renames:  imprt.Renames,
+	}
+}
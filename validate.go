@@ -0,0 +1,323 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	doublestarx "github.com/bmatcuk/doublestar/v4"
+	"gopkg.in/yaml.v3"
+
+	"go.xrstf.de/gimps/pkg/gimps"
+)
+
+var predefinedSets = []string{gimps.SetStd, gimps.SetProject, gimps.SetExternal}
+
+// configValidator checks a decoded configuration for semantic errors. To
+// point the user to the right place, each error is reported together with
+// the position of the offending node in the YAML document.
+type configValidator struct {
+	filename string
+	errs     []error
+	warnings []string
+}
+
+// validateConfiguration returns all problems found in the configuration,
+// joined into a single error, and warnings about settings that are valid,
+// but likely not what the user intended. root must be the document node
+// the config was decoded from.
+func validateConfiguration(filename string, c *Config, root *yaml.Node) ([]string, error) {
+	v := &configValidator{
+		filename: filename,
+	}
+
+	doc := root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+
+	v.validateSets(c, mappingValue(doc, "sets"))
+	v.validateImportOrder(c, doc, mappingValue(doc, "importOrder"))
+	v.validateAliasRules(c.AliasRules, mappingValue(doc, "aliasRules"), "alias rule")
+	v.validateAliasRules(c.RequiredAliases, mappingValue(doc, "requiredAliases"), "required alias")
+	v.validateForbiddenAliases(c, mappingValue(doc, "forbiddenAliases"))
+	v.validateExcludes(c, mappingValue(doc, "exclude"))
+
+	if c.StdDetection != "" && !slices.Contains(gimps.StdDetectionModes, c.StdDetection) {
+		v.errorf(mappingValue(doc, "stdDetection"), "invalid stdDetection %q, must be one of %s", c.StdDetection, strings.Join(gimps.StdDetectionModes, ", "))
+	}
+
+	if c.AliasConflicts != "" && !slices.Contains(gimps.AliasConflictStrategies, c.AliasConflicts) {
+		v.errorf(mappingValue(doc, "aliasConflicts"), "invalid aliasConflicts %q, must be one of %s", c.AliasConflicts, strings.Join(gimps.AliasConflictStrategies, ", "))
+	}
+
+	if c.Matching != "" && !slices.Contains(gimps.SetMatchingModes, c.Matching) {
+		v.errorf(mappingValue(doc, "matching"), "invalid matching %q, must be one of %s", c.Matching, strings.Join(gimps.SetMatchingModes, ", "))
+	}
+
+	if c.Matching == gimps.SetMatchingSpecific {
+		v.validateSetOverlaps(c, mappingValue(doc, "sets"))
+	}
+
+	return v.warnings, errors.Join(v.errs...)
+}
+
+func (v *configValidator) errorf(node *yaml.Node, format string, args ...any) {
+	v.errs = append(v.errs, errors.New(v.position(node)+fmt.Sprintf(format, args...)))
+}
+
+func (v *configValidator) warnf(node *yaml.Node, format string, args ...any) {
+	v.warnings = append(v.warnings, v.position(node)+fmt.Sprintf(format, args...))
+}
+
+func (v *configValidator) position(node *yaml.Node) string {
+	if node != nil {
+		return fmt.Sprintf("%s:%d:%d: ", v.filename, node.Line, node.Column)
+	}
+
+	return v.filename + ": "
+}
+
+func (v *configValidator) validateSets(c *Config, setsNode *yaml.Node) {
+	firstDefinition := map[string]*yaml.Node{}
+
+	for i, set := range c.Sets {
+		setNode := sequenceItem(setsNode, i)
+		nameNode := mappingValue(setNode, "name")
+
+		switch {
+		case set.Name == "":
+			v.errorf(setNode, "set has no name")
+
+		case slices.Contains(predefinedSets, set.Name) && (len(set.Patterns) > 0 || set.GoMod != ""):
+			v.errorf(nameNode, "set %q is predefined and cannot be configured", set.Name)
+
+		case firstDefinition[set.Name] != nil:
+			first := firstDefinition[set.Name]
+			v.errorf(nameNode, "duplicate set %q, already defined in line %d", set.Name, first.Line)
+
+		default:
+			firstDefinition[set.Name] = nameNode
+		}
+
+		if set.GoMod != "" && !slices.Contains(gimps.GoModSources, set.GoMod) {
+			v.errorf(mappingValue(setNode, "goMod"), "set %q has an invalid goMod %q, must be one of %s", set.Name, set.GoMod, strings.Join(gimps.GoModSources, ", "))
+		}
+
+		if set.SubgroupBy != "" && !slices.Contains(gimps.SubgroupModes, set.SubgroupBy) {
+			v.errorf(mappingValue(setNode, "subgroupBy"), "set %q has an invalid subgroupBy %q, must be one of %s", set.Name, set.SubgroupBy, strings.Join(gimps.SubgroupModes, ", "))
+		}
+
+		// predefined sets can only configure their subgroups
+		if len(set.Patterns) == 0 && set.GoMod == "" && !slices.Contains(predefinedSets, set.Name) {
+			v.errorf(setNode, "set %q has no patterns", set.Name)
+		}
+
+		patternsNode := mappingValue(setNode, "patterns")
+		includes := 0
+
+		for j, pattern := range set.Patterns {
+			patternNode := sequenceItem(patternsNode, j)
+
+			if pattern == "" {
+				v.errorf(patternNode, "set %q contains an empty pattern", set.Name)
+				continue
+			}
+
+			if !gimps.IsNegatedSetPattern(pattern) {
+				includes++
+			}
+
+			if err := gimps.ValidateSetPattern(pattern); err != nil {
+				v.errorf(patternNode, "set %q contains an invalid pattern %q: %v", set.Name, pattern, err)
+			}
+		}
+
+		if len(set.Patterns) > 0 && includes == 0 && set.GoMod == "" {
+			v.errorf(patternsNode, "set %q has only exclusion patterns and would never match", set.Name)
+		}
+	}
+}
+
+// validateSetOverlaps warns about equally specific patterns in different
+// sets, as with the specific matching mode the order of sets still decides
+// which of them an import belongs to.
+func (v *configValidator) validateSetOverlaps(c *Config, setsNode *yaml.Node) {
+	for _, overlap := range gimps.FindSetOverlaps(c.Sets) {
+		set := c.Sets[overlap.Set]
+		other := c.Sets[overlap.OtherSet]
+		patternNode := sequenceItem(mappingValue(sequenceItem(setsNode, overlap.OtherSet), "patterns"), overlap.OtherPattern)
+
+		v.warnf(patternNode, "pattern %q of set %q is as specific as pattern %q of set %q, imports matching both belong to %q", other.Patterns[overlap.OtherPattern], other.Name, set.Patterns[overlap.Pattern], set.Name, set.Name)
+	}
+}
+
+func (v *configValidator) validateImportOrder(c *Config, doc *yaml.Node, orderNode *yaml.Node) {
+	knownSets := slices.Clone(predefinedSets)
+	for _, set := range c.Sets {
+		knownSets = append(knownSets, set.Name)
+	}
+
+	// gimps falls back to a default order if nothing is configured
+	importOrder := c.ImportOrder
+	if len(importOrder) == 0 {
+		importOrder = []string{gimps.SetStd, gimps.SetProject, gimps.SetExternal}
+	}
+
+	listed := map[string]*yaml.Node{}
+	for i, setName := range importOrder {
+		itemNode := sequenceItem(orderNode, i)
+
+		if !slices.Contains(knownSets, setName) {
+			v.errorf(itemNode, "importOrder refers to unknown set %q", setName)
+		}
+
+		if first, exists := listed[setName]; exists {
+			line := 0
+			if first != nil {
+				line = first.Line
+			}
+
+			v.errorf(itemNode, "set %q is listed multiple times in importOrder, first in line %d", setName, line)
+			continue
+		}
+
+		listed[setName] = itemNode
+	}
+
+	// imports are grouped by the order, so any set that is not listed
+	// needs a fallback or files with matching imports cannot be formatted
+	if c.FallbackSet != "" {
+		if _, ok := listed[c.FallbackSet]; !ok {
+			v.errorf(mappingValue(doc, "fallbackSet"), "fallbackSet %q is not listed in importOrder", c.FallbackSet)
+		}
+
+		return
+	}
+
+	setsNode := mappingValue(doc, "sets")
+
+	for _, setName := range predefinedSets {
+		if _, ok := listed[setName]; !ok {
+			v.errorf(orderNode, "predefined set %q is not listed in importOrder and no fallbackSet is configured", setName)
+		}
+	}
+
+	for i, set := range c.Sets {
+		if _, ok := listed[set.Name]; !ok && set.Name != "" && !slices.Contains(predefinedSets, set.Name) {
+			v.errorf(mappingValue(sequenceItem(setsNode, i), "name"), "set %q is not listed in importOrder and no fallbackSet is configured", set.Name)
+		}
+	}
+}
+
+// validateAliasRules checks the given rules, which are either the aliasRules
+// or the requiredAliases; kind is used to refer to a rule in error messages.
+func (v *configValidator) validateAliasRules(rules []gimps.AliasRule, rulesNode *yaml.Node, kind string) {
+	firstDefinition := map[string]*yaml.Node{}
+
+	for i, rule := range rules {
+		ruleNode := sequenceItem(rulesNode, i)
+		nameNode := mappingValue(ruleNode, "name")
+
+		if rule.Name == "" {
+			v.errorf(ruleNode, "%s %d has no name", kind, i+1)
+		} else if first := firstDefinition[rule.Name]; first != nil {
+			v.errorf(nameNode, "duplicate %s %q, already defined in line %d", kind, rule.Name, first.Line)
+		} else {
+			firstDefinition[rule.Name] = nameNode
+		}
+
+		exprNode := mappingValue(ruleNode, "expr")
+		if rule.Expression == "" {
+			v.errorf(ruleNode, "%s %q has no expression", kind, rule.Name)
+		} else if _, err := regexp.Compile(rule.Expression); err != nil {
+			v.errorf(exprNode, "%s %q has an invalid expression: %v", kind, rule.Name, err)
+		}
+
+		switch rule.Type {
+		case "", gimps.AliasRuleTypeAlias:
+			if rule.Alias == "" {
+				v.errorf(ruleNode, "%s %q has no alias", kind, rule.Name)
+			} else if strings.Contains(rule.Alias, "{{") {
+				if _, err := gimps.ParseAliasTemplate(rule.Name, rule.Alias); err != nil {
+					v.errorf(mappingValue(ruleNode, "alias"), "%s %q has an invalid alias template: %v", kind, rule.Name, err)
+				}
+			}
+
+		case gimps.AliasRuleTypeUnalias:
+			if rule.Alias != "" {
+				v.errorf(mappingValue(ruleNode, "alias"), "%s %q removes aliases and must not configure an alias", kind, rule.Name)
+			}
+
+		default:
+			v.errorf(mappingValue(ruleNode, "type"), "%s %q has an invalid type %q, must be %q or %q", kind, rule.Name, rule.Type, gimps.AliasRuleTypeAlias, gimps.AliasRuleTypeUnalias)
+		}
+	}
+}
+
+func (v *configValidator) validateForbiddenAliases(c *Config, rulesNode *yaml.Node) {
+	firstDefinition := map[string]*yaml.Node{}
+
+	for i, rule := range c.ForbiddenAliases {
+		ruleNode := sequenceItem(rulesNode, i)
+		nameNode := mappingValue(ruleNode, "name")
+
+		if rule.Name == "" {
+			v.errorf(ruleNode, "forbidden alias %d has no name", i+1)
+		} else if first := firstDefinition[rule.Name]; first != nil {
+			v.errorf(nameNode, "duplicate forbidden alias %q, already defined in line %d", rule.Name, first.Line)
+		} else {
+			firstDefinition[rule.Name] = nameNode
+		}
+
+		if rule.Alias == "" {
+			v.errorf(ruleNode, "forbidden alias %q has no alias expression", rule.Name)
+		} else if _, err := regexp.Compile(rule.Alias); err != nil {
+			v.errorf(mappingValue(ruleNode, "alias"), "forbidden alias %q has an invalid alias expression: %v", rule.Name, err)
+		}
+
+		if rule.Except != "" {
+			if _, err := regexp.Compile(rule.Except); err != nil {
+				v.errorf(mappingValue(ruleNode, "except"), "forbidden alias %q has an invalid except expression: %v", rule.Name, err)
+			}
+		}
+	}
+}
+
+func (v *configValidator) validateExcludes(c *Config, excludeNode *yaml.Node) {
+	for i, exclude := range c.Exclude {
+		if !doublestarx.ValidatePattern(exclude) {
+			v.errorf(sequenceItem(excludeNode, i), "invalid exclude pattern %q", exclude)
+		}
+	}
+}
+
+// mappingValue returns the value node for the given key, or nil if the
+// node is not a mapping or does not contain the key.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// sequenceItem returns the n-th item of a sequence node, or nil.
+func sequenceItem(node *yaml.Node, n int) *yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode || n >= len(node.Content) {
+		return nil
+	}
+
+	return node.Content[n]
+}
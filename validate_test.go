@@ -0,0 +1,338 @@
+// SPDX-FileCopyrightText: 2023 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDecodeConfiguration(t *testing.T) {
+	testcases := []struct {
+		name     string
+		config   string
+		expected []string
+		warnings []string
+	}{
+		{
+			name:   "empty config",
+			config: "",
+		},
+		{
+			name: "valid config",
+			config: `
+importOrder: [std, external, project, kubernetes]
+sets:
+  - name: kubernetes
+    patterns:
+      - 'k8s.io/**'
+aliasRules:
+  - name: k8s-api
+    expr: '^k8s.io/api/([a-z0-9-]+)/(v[a-z0-9-]+)$'
+    alias: '$1$2'
+`,
+		},
+		{
+			name:     "unknown field",
+			config:   "importorder: [std, project, external]",
+			expected: []string{"line 1: field importorder not found"},
+		},
+		{
+			name: "unknown set in importOrder",
+			config: `
+importOrder: [std, project, external, kubernetes]
+`,
+			expected: []string{`.gimps.yaml:2:39: importOrder refers to unknown set "kubernetes"`},
+		},
+		{
+			name: "duplicate set in importOrder",
+			config: `
+importOrder: [std, project, external, std]
+`,
+			expected: []string{`.gimps.yaml:2:39: set "std" is listed multiple times in importOrder, first in line 2`},
+		},
+		{
+			name: "sets missing from importOrder",
+			config: `
+importOrder: [std, project]
+sets:
+  - name: kubernetes
+    patterns: ['k8s.io/**']
+`,
+			expected: []string{
+				`.gimps.yaml:2:14: predefined set "external" is not listed in importOrder and no fallbackSet is configured`,
+				`.gimps.yaml:4:11: set "kubernetes" is not listed in importOrder and no fallbackSet is configured`,
+			},
+		},
+		{
+			name: "sets missing from importOrder with fallback",
+			config: `
+importOrder: [std, project]
+fallbackSet: project
+sets:
+  - name: kubernetes
+    patterns: ['k8s.io/**']
+`,
+		},
+		{
+			name: "unknown fallback set",
+			config: `
+importOrder: [std, project, external]
+fallbackSet: kubernetes
+`,
+			expected: []string{`.gimps.yaml:3:14: fallbackSet "kubernetes" is not listed in importOrder`},
+		},
+		{
+			name: "README example",
+			config: `
+importOrder: [std, external, kubermatic, kubernetes]
+fallbackSet: kubermatic
+sets:
+  - name: kubermatic
+    patterns:
+      - 'k8c.io/**'
+      - 'github.com/kubermatic/**'
+  - name: kubernetes
+    patterns:
+      - 'k8s.io/**'
+      - '*.k8s.io/**'
+`,
+		},
+		{
+			name: "broken sets",
+			config: `
+importOrder: [std, project, external, kubernetes]
+sets:
+  - name: kubernetes
+    patterns: ['k8s.io/**']
+  - name: kubernetes
+    patterns: ['', 'k8s.io/[**']
+  - name: std
+    patterns: ['fmt']
+  - name: empty
+`,
+			expected: []string{
+				`.gimps.yaml:6:11: duplicate set "kubernetes", already defined in line 4`,
+				`.gimps.yaml:7:16: set "kubernetes" contains an empty pattern`,
+				`.gimps.yaml:7:20: set "kubernetes" contains an invalid pattern "k8s.io/[**": invalid glob`,
+				`.gimps.yaml:8:11: set "std" is predefined and cannot be configured`,
+				`.gimps.yaml:10:5: set "empty" has no patterns`,
+				`.gimps.yaml:10:11: set "empty" is not listed in importOrder and no fallbackSet is configured`,
+			},
+		},
+		{
+			name: "broken set patterns",
+			config: `
+importOrder: [std, project, external, kubernetes, excluded]
+sets:
+  - name: kubernetes
+    patterns: ['regex:^k8s\.io/(api', '!regex:', '!']
+  - name: excluded
+    patterns: ['!k8s.io/**']
+`,
+			expected: []string{
+				`.gimps.yaml:5:16: set "kubernetes" contains an invalid pattern "regex:^k8s\\.io/(api": error parsing regexp: missing closing ): ` + "`^k8s\\.io/(api`",
+				`.gimps.yaml:5:39: set "kubernetes" contains an invalid pattern "!regex:": empty regular expression`,
+				`.gimps.yaml:5:50: set "kubernetes" contains an invalid pattern "!": empty pattern`,
+				`.gimps.yaml:7:15: set "excluded" has only exclusion patterns and would never match`,
+			},
+		},
+		{
+			name: "overlapping sets with first matching",
+			config: `
+importOrder: [std, project, external, kubermatic, machine-controller]
+sets:
+  - name: kubermatic
+    patterns: ['github.com/kubermatic/**']
+  - name: machine-controller
+    patterns: ['github.com/kubermatic/**']
+`,
+		},
+		{
+			name: "overlapping sets with specific matching",
+			config: `
+importOrder: [std, project, external, kubermatic, machine-controller]
+matching: specific
+sets:
+  - name: kubermatic
+    patterns: ['github.com/kubermatic/**', '!github.com/kubermatic/kubermatic/**']
+  - name: machine-controller
+    patterns:
+      - 'github.com/kubermatic/machine-controller/**'
+      - 'regex:^github\.com/kubermatic/'
+      - '!github.com/kubermatic/**'
+`,
+			warnings: []string{
+				`.gimps.yaml:10:9: pattern "regex:^github\\.com/kubermatic/" of set "machine-controller" is as specific as pattern "github.com/kubermatic/**" of set "kubermatic", imports matching both belong to "kubermatic"`,
+			},
+		},
+		{
+			name: "go.mod sets",
+			config: `
+importOrder: [std, project, external, direct, forks]
+sets:
+  - name: direct
+    goMod: direct
+  - name: forks
+    goMod: replaced
+    patterns: ['!github.com/kubermatic/**']
+  - name: broken
+    goMod: required
+`,
+			expected: []string{
+				`.gimps.yaml:10:12: set "broken" has an invalid goMod "required", must be one of direct, indirect, replaced`,
+			},
+		},
+		{
+			name: "subgroups",
+			config: `
+sets:
+  - name: external
+    subgroupBy: module
+  - name: std
+    subgroupBy: domain
+  - name: project
+    subgroupBy: none
+`,
+		},
+		{
+			name: "broken subgroups",
+			config: `
+importOrder: [std, project, external, kubernetes]
+sets:
+  - name: kubernetes
+    patterns: ['k8s.io/**']
+    subgroupBy: package
+  - name: external
+    subgroupBy: module
+    patterns: ['github.com/**']
+`,
+			expected: []string{
+				`.gimps.yaml:6:17: set "kubernetes" has an invalid subgroupBy "package", must be one of none, module, domain`,
+				`.gimps.yaml:7:11: set "external" is predefined and cannot be configured`,
+			},
+		},
+		{
+			name: "invalid matching",
+			config: `
+matching: best
+`,
+			expected: []string{`.gimps.yaml:2:11: invalid matching "best", must be one of first, specific`},
+		},
+		{
+			name: "broken alias rules",
+			config: `
+aliasRules:
+  - name: foo
+    expr: 'foo'
+    alias: 'foo'
+  - name: foo
+    expr: 'foo('
+  - alias: 'bar'
+`,
+			expected: []string{
+				`.gimps.yaml:6:11: duplicate alias rule "foo", already defined in line 3`,
+				`.gimps.yaml:7:11: alias rule "foo" has an invalid expression: error parsing regexp: missing closing ): ` + "`foo(`",
+				`.gimps.yaml:6:5: alias rule "foo" has no alias`,
+				`.gimps.yaml:8:5: alias rule 3 has no name`,
+				`.gimps.yaml:8:5: alias rule "" has no expression`,
+			},
+		},
+		{
+			name: "broken alias rule types",
+			config: `
+aliasRules:
+  - name: foo
+    type: unalias
+    expr: 'foo'
+    alias: 'foo'
+  - name: bar
+    type: rename
+    expr: 'bar'
+    alias: 'bar'
+  - name: baz
+    expr: 'baz'
+    alias: '{{ .Path | unknown }}'
+`,
+			expected: []string{
+				`.gimps.yaml:6:12: alias rule "foo" removes aliases and must not configure an alias`,
+				`.gimps.yaml:8:11: alias rule "bar" has an invalid type "rename", must be "alias" or "unalias"`,
+				`.gimps.yaml:13:12: alias rule "baz" has an invalid alias template: template: baz:1: function "unknown" not defined`,
+			},
+		},
+		{
+			name:   "invalid std detection",
+			config: `stdDetection: guess`,
+			expected: []string{
+				`.gimps.yaml:1:15: invalid stdDetection "guess", must be one of static, toolchain, heuristic`,
+			},
+		},
+		{
+			name: "invalid exclude pattern",
+			config: `
+exclude:
+  - 'hack/**'
+  - 'vendor/[**'
+`,
+			expected: []string{
+				`.gimps.yaml:4:5: invalid exclude pattern "vendor/[**"`,
+			},
+		},
+		{
+			name:   "invalid alias conflict strategy",
+			config: `aliasConflicts: ignore`,
+			expected: []string{
+				`.gimps.yaml:1:17: invalid aliasConflicts "ignore", must be one of fail, skip-file, skip-rule, disambiguate`,
+			},
+		},
+		{
+			name: "broken lint rules",
+			config: `
+forbiddenAliases:
+  - name: no-versions
+    alias: '^v[0-9]+$'
+    except: 'foo('
+  - name: no-versions
+requiredAliases:
+  - name: metav1
+    expr: '^k8s.io/apimachinery/pkg/apis/meta/v1$'
+`,
+			expected: []string{
+				`.gimps.yaml:5:13: forbidden alias "no-versions" has an invalid except expression: error parsing regexp: missing closing ): ` + "`foo(`",
+				`.gimps.yaml:6:11: duplicate forbidden alias "no-versions", already defined in line 3`,
+				`.gimps.yaml:6:5: forbidden alias "no-versions" has no alias expression`,
+				`.gimps.yaml:8:5: required alias "metav1" has no alias`,
+			},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, warnings, err := decodeConfiguration(".gimps.yaml", []byte(tt.config))
+
+			if len(tt.expected) == 0 {
+				if err != nil {
+					t.Fatalf("should not have errored, but got %v", err)
+				}
+
+				if !slices.Equal(warnings, tt.warnings) {
+					t.Fatalf("Expected warnings\n\n%s\n\nbut got\n\n%s", strings.Join(tt.warnings, "\n"), strings.Join(warnings, "\n"))
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Fatal("should have errored, but did not")
+			}
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("Expected error to contain\n\n%s\n\nbut got\n\n%v", expected, err)
+				}
+			}
+		})
+	}
+}